@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/db/book"
+	"github.com/lgdzz/vingo-utils-v2/db/migrate"
 	"github.com/lgdzz/vingo-utils-v2/db/mysql"
 	"github.com/lgdzz/vingo-utils-v2/db/pgsql"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
 	"log"
 	"os"
 	"os/exec"
@@ -16,6 +20,7 @@ type Options struct {
 	Enable     bool
 	DbApi      *mysql.DbApi
 	PgSqlDbApi *pgsql.DbApi
+	Migrate    migrate.Option // 数据库迁移配置，FS通常为embed.FS
 	Register   func()
 }
 
@@ -26,8 +31,16 @@ func InitCli(options Options) {
 	model := flag.String("model", "", "生成数据库模型，支持多个表生成，格式：table1,table2")
 	flag.StringVar(model, "m", "", "生成数据库模型，支持多个表生成，格式：table1,table2")
 
-	dbbook := flag.Bool("dbbook", false, "生成数据库字典")
-	flag.BoolVar(dbbook, "d", false, "生成数据库字典")
+	modelService := flag.Bool("model-service", false, "生成数据库模型时同时生成service文件（仅mysql）")
+	modelRouter := flag.Bool("model-router", false, "生成数据库模型时同时生成service和router文件（仅mysql）")
+	modelRegen := flag.Bool("model-regen", false, "重新生成模型，结构体写入表名_gen.go并覆盖，保留表名.go中的自定义代码，输出字段差异（仅mysql）")
+	modelAll := flag.Bool("model-all", false, "生成当前库全部表的模型，配合-model-include、-model-exclude过滤")
+	modelInclude := flag.String("model-include", "", "生成全部表时包含的表名模式，格式：sys_*,biz_*")
+	modelExclude := flag.String("model-exclude", "", "生成全部表时排除的表名模式，格式：*_log,tmp_*")
+
+	dbbook := new(bookFormat)
+	flag.Var(dbbook, "dbbook", "生成数据库字典，默认html，指定格式：-dbbook=html,md,json,xlsx")
+	flag.Var(dbbook, "d", "生成数据库字典，默认html，指定格式：-d=html,md,json,xlsx")
 
 	secret := flag.Bool("secret", false, "生成字符串加解密secret")
 	flag.BoolVar(secret, "s", false, "生成字符串加解密secret")
@@ -37,6 +50,11 @@ func InitCli(options Options) {
 
 	updateVingo := flag.String("v2", "", "更新vingo-v2版本")
 
+	migrateCmd := flag.String("migrate", "", "数据库迁移，参数：up|down|status|create")
+	migrateName := flag.String("migrate-name", "", "创建迁移文件的名称，配合-migrate create使用")
+	migrateSteps := flag.Int("migrate-steps", 1, "回滚的迁移数量，配合-migrate down使用")
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "只输出迁移sql，不执行")
+
 	if options.Register != nil {
 		options.Register()
 	}
@@ -53,28 +71,56 @@ func InitCli(options Options) {
 	}
 
 	// 创建数据库字典
-	if *dbbook {
+	if *dbbook != "" {
+		formats, err := book.ParseFormats(string(*dbbook))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(0)
+		}
 		if options.DbApi != nil {
-			err := options.DbApi.BuildBook()
-			if err != nil {
-				fmt.Println(err)
-			}
+			err = options.DbApi.BuildBook(formats...)
 		} else if options.PgSqlDbApi != nil {
-			err := options.PgSqlDbApi.BuildBook()
-			if err != nil {
-				fmt.Println(err)
-			}
+			err = options.PgSqlDbApi.BuildBook(formats...)
+		}
+		if err != nil {
+			fmt.Println(err)
 		}
 		os.Exit(0)
 	}
 
 	// 创建数据表模型文件
-	if *model != "" {
+	if *model != "" || *modelAll {
+		var tables []string
+		if *model != "" {
+			tables = strings.Split(*model, ",")
+		}
 		if options.DbApi != nil {
-			_, _ = options.DbApi.CreateDbModel(strings.Split(*model, ",")...)
+			_, _ = options.DbApi.CreateDbModelWithOption(mysql.ModelOption{
+				Service:    *modelService,
+				Router:     *modelRouter,
+				Regenerate: *modelRegen,
+				Include:    splitPatterns(*modelInclude),
+				Exclude:    splitPatterns(*modelExclude),
+			}, tables...)
 		} else if options.PgSqlDbApi != nil {
-			_, _ = options.PgSqlDbApi.CreateDbModel(strings.Split(*model, ",")...)
+			if *modelRegen || *modelService || *modelRouter {
+				fmt.Println("pgsql暂不支持-model-regen、-model-service、-model-router参数")
+				os.Exit(1)
+			}
+			_, _ = options.PgSqlDbApi.CreateDbModelWithOption(pgsql.ModelOption{
+				Include: splitPatterns(*modelInclude),
+				Exclude: splitPatterns(*modelExclude),
+			}, tables...)
+		}
+		os.Exit(0)
+	}
 
+	// 数据库迁移
+	if *migrateCmd != "" {
+		options.Migrate.DryRun = *migrateDryRun
+		if err := RunMigrate(options, *migrateCmd, *migrateName, *migrateSteps); err != nil {
+			log.Println(err.Error())
+			os.Exit(1)
 		}
 		os.Exit(0)
 	}
@@ -94,6 +140,98 @@ func InitCli(options Options) {
 
 }
 
+// 数据库字典格式参数，兼容布尔写法：-dbbook 等同于 -dbbook=html
+type bookFormat string
+
+func (s *bookFormat) String() string {
+	return string(*s)
+}
+
+func (s *bookFormat) Set(value string) error {
+	switch value {
+	case "true":
+		value = book.FormatHtml
+	case "false":
+		value = ""
+	}
+	*s = bookFormat(value)
+	return nil
+}
+
+func (s *bookFormat) IsBoolFlag() bool {
+	return true
+}
+
+// 拆分逗号分隔的表名模式
+func splitPatterns(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// 执行数据库迁移命令，失败时返回错误
+func RunMigrate(options Options, command string, name string, steps int) error {
+	if command == "create" {
+		up, down, err := migrate.Create(options.Migrate.Dir, name)
+		if err != nil {
+			return fmt.Errorf("创建迁移文件错误：%w", err)
+		}
+		log.Println("迁移文件：", up)
+		log.Println("回滚文件：", down)
+		return nil
+	}
+
+	var db *gorm.DB
+	if options.DbApi != nil {
+		db = options.DbApi.DB
+	} else if options.PgSqlDbApi != nil {
+		db = options.PgSqlDbApi.DB
+	} else {
+		return errors.New("未配置数据库连接")
+	}
+	migrator, err := migrate.New(db, options.Migrate)
+	if err != nil {
+		return fmt.Errorf("加载迁移错误：%w", err)
+	}
+
+	var migrations []migrate.Migration
+	switch command {
+	case "up":
+		migrations, err = migrator.Up()
+	case "down":
+		migrations, err = migrator.Down(steps)
+	case "status":
+		var status []migrate.Status
+		if status, err = migrator.Status(); err == nil {
+			for _, item := range status {
+				var text = "未执行"
+				if item.Applied {
+					text = "已执行 " + item.AppliedAt.Format(vingo.DatetimeFormat)
+				}
+				if item.Missing {
+					text += "（迁移文件不存在）"
+				}
+				fmt.Printf("%v_%v\t%v\n", item.Version, item.Name, text)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的迁移命令：%v", command)
+	}
+	// 失败前已完成的迁移
+	var text = vingo.SY(command == "up", "已执行", "已回滚")
+	if options.Migrate.DryRun {
+		text = vingo.SY(command == "up", "待执行", "待回滚")
+	}
+	for _, item := range migrations {
+		log.Printf("%v %v_%v\n", text, item.Version, item.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("执行迁移错误：%w", err)
+	}
+	return nil
+}
+
 func BuildProject(value string, version string) {
 	var goos string
 	var osName string
@@ -0,0 +1,389 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据变更审计插件，记录模型更新、删除前后的字段变化及操作人
+// *****************************************************************************
+
+package audit
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/db/resolver"
+	"github.com/lgdzz/vingo-utils-v2/db/tenant"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 操作类型
+const (
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+const (
+	skipKey     = "audit:skip"
+	snapshotKey = "audit:snapshot"
+)
+
+// 需要审计的模型实现此接口，返回false时不记录
+//
+//	func (s *Article) Auditable() bool {
+//		return true
+//	}
+type Model interface {
+	Auditable() bool
+}
+
+// 操作人
+type Actor struct {
+	UserId   int
+	AccId    int
+	OrgId    int
+	RealName string
+	Ip       string
+}
+
+// 从请求上下文读取操作人
+func NewActor(c *vingo.Context) *Actor {
+	return &Actor{
+		UserId:   c.GetUserId(),
+		AccId:    c.GetAccId(),
+		OrgId:    c.GetOrgId(),
+		RealName: c.GetRealName(),
+		Ip:       c.GetRealClientIP(),
+	}
+}
+
+type actorKey struct{}
+
+// 将操作人写入context
+func WithActor(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// 读取context中的操作人
+func ActorFromContext(ctx context.Context) (*Actor, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	actor, ok := ctx.Value(actorKey{}).(*Actor)
+	return actor, ok && actor != nil
+}
+
+// 生成携带当前操作人的context，用于db.WithContext，可与tenant.Context组合：
+// audit.WithActor(tenant.Context(c), audit.NewActor(c))
+func Context(c *vingo.Context) context.Context {
+	return WithActor(c.Request.Context(), NewActor(c))
+}
+
+// 跳过审计，用于批量同步等不需要记录的场景
+func Skip(db *gorm.DB) *gorm.DB {
+	return db.Set(skipKey, true)
+}
+
+// 字段变化
+type Change struct {
+	Column string `json:"column"` // 数据库字段
+	Label  string `json:"label"`  // 字段名称，取自diff标签或gorm注释
+	Old    any    `json:"old"`
+	New    any    `json:"new"`
+}
+
+type Changes []Change
+
+func (s Changes) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *Changes) Scan(value any) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case nil:
+		*s = nil
+		return nil
+	}
+	return errors.New("Scan source is not []byte")
+}
+
+// 审计记录
+type Record struct {
+	Id         uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Table      string          `gorm:"column:table_name;size:64;index:idx_audit_target,priority:1" json:"table"`
+	PrimaryKey string          `gorm:"column:primary_key;size:64;index:idx_audit_target,priority:2" json:"primaryKey"`
+	Action     string          `gorm:"size:16" json:"action"`
+	Changes    Changes         `gorm:"type:json" json:"changes"`
+	Content    string          `gorm:"type:text" json:"content"` // 变更说明
+	UserId     int             `gorm:"index" json:"userId"`
+	AccId      int             `json:"accId"`
+	OrgId      int             `json:"orgId"`
+	RealName   string          `gorm:"size:64" json:"realName"`
+	Ip         string          `gorm:"size:64" json:"ip"`
+	CreatedAt  vingo.LocalTime `gorm:"index" json:"createdAt"`
+}
+
+func (s *Record) TableName() string {
+	return "audit_log"
+}
+
+type Config struct {
+	Table   string // 审计表名，默认audit_log
+	MaxRows int    // 单次更新/删除最多记录的行数，超出时不记录，默认500
+	Strict  bool   // 为true时审计记录写入失败会返回错误（事务中将回滚），默认只输出日志
+}
+
+type Plugin struct {
+	Config Config
+}
+
+// 创建审计插件，db.Use(audit.New(audit.Config{}))，审计表可通过audit.AutoMigrate创建
+func New(config Config) *Plugin {
+	if config.Table == "" {
+		config.Table = "audit_log"
+	}
+	if config.MaxRows <= 0 {
+		config.MaxRows = 500
+	}
+	return &Plugin{Config: config}
+}
+
+// 创建审计表
+func AutoMigrate(db *gorm.DB, table ...string) error {
+	if len(table) > 0 && table[0] != "" {
+		db = db.Table(table[0])
+	}
+	return db.AutoMigrate(&Record{})
+}
+
+func (s *Plugin) Name() string {
+	return "vingo:audit"
+}
+
+func (s *Plugin) Initialize(db *gorm.DB) error {
+	// 在数据隔离之后读取变更前数据，确保读取范围与实际更新范围一致
+	return errors.Join(
+		db.Callback().Update().Before("gorm:update").After("tenant:update").Register("audit:before_update", s.before),
+		db.Callback().Update().After("gorm:update").Register("audit:after_update", s.afterUpdate),
+		db.Callback().Delete().Before("gorm:delete").After("tenant:delete").Register("audit:before_delete", s.before),
+		db.Callback().Delete().After("gorm:delete").Register("audit:after_delete", s.afterDelete),
+	)
+}
+
+func (s *Plugin) enabled(db *gorm.DB) bool {
+	var stmt = db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 || stmt.Schema.PrioritizedPrimaryField == nil {
+		return false
+	}
+	if skip, ok := db.Get(skipKey); ok && skip == true {
+		return false
+	}
+	model, ok := reflect.New(stmt.Schema.ModelType).Interface().(Model)
+	return ok && model.Auditable()
+}
+
+// 读取变更前的数据
+func (s *Plugin) before(db *gorm.DB) {
+	if !s.enabled(db) {
+		return
+	}
+	var exprs = conditions(db.Statement)
+	if len(exprs) == 0 {
+		return
+	}
+	rows, err := s.find(db, exprs, false)
+	if err != nil {
+		vingo.LogError(fmt.Sprintf("[审计]读取变更前数据错误：%v", err.Error()))
+		return
+	}
+	if rows.Len() > s.Config.MaxRows {
+		vingo.LogInfo(fmt.Sprintf("[审计]%v变更行数超过%v，不记录", db.Statement.Table, s.Config.MaxRows))
+		return
+	}
+	if rows.Len() > 0 {
+		db.InstanceSet(snapshotKey, rows)
+	}
+}
+
+func (s *Plugin) afterUpdate(db *gorm.DB) {
+	olds, ok := snapshot(db)
+	if !ok {
+		return
+	}
+	var sch = db.Statement.Schema
+	var pk = sch.PrioritizedPrimaryField
+	var values = make([]any, olds.Len())
+	for i := 0; i < olds.Len(); i++ {
+		values[i], _ = pk.ValueOf(db.Statement.Context, olds.Index(i))
+	}
+	news, err := s.find(db, []clause.Expression{clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: values}}, true)
+	if err != nil {
+		vingo.LogError(fmt.Sprintf("[审计]读取变更后数据错误：%v", err.Error()))
+		return
+	}
+	var newRows = make(map[string]reflect.Value, news.Len())
+	for i := 0; i < news.Len(); i++ {
+		value, _ := pk.ValueOf(db.Statement.Context, news.Index(i))
+		newRows[fmt.Sprint(value)] = news.Index(i)
+	}
+
+	var records = make([]Record, 0, olds.Len())
+	for i := 0; i < olds.Len(); i++ {
+		var key = fmt.Sprint(values[i])
+		newRow, ok := newRows[key]
+		if !ok {
+			continue
+		}
+		var box = vingo.DiffBox{Old: olds.Index(i).Interface(), New: newRow.Interface()}
+		box.Compare()
+		var changes = make(Changes, 0)
+		var content string
+		for _, item := range box.Items() {
+			// 嵌套字段以数据库字段开头，如：extra.name
+			name, _, _ := strings.Cut(item.Column, ".")
+			field := sch.LookUpField(name)
+			if field == nil || field.DBName == "" {
+				continue
+			}
+			var column = field.DBName
+			if _, rest, ok := strings.Cut(item.Path, "."); ok {
+				column += "." + rest
+			}
+			changes = append(changes, Change{Column: column, Label: item.Label, Old: item.OldValue, New: item.NewValue})
+			content += item.Message
+		}
+		if len(changes) > 0 {
+			records = append(records, s.record(db, ActionUpdate, key, changes, content))
+		}
+	}
+	s.write(db, records)
+}
+
+func (s *Plugin) afterDelete(db *gorm.DB) {
+	olds, ok := snapshot(db)
+	if !ok {
+		return
+	}
+	var sch = db.Statement.Schema
+	var records = make([]Record, 0, olds.Len())
+	for i := 0; i < olds.Len(); i++ {
+		var row = olds.Index(i)
+		var changes = make(Changes, 0, len(sch.Fields))
+		for _, field := range sch.Fields {
+			if field.DBName == "" || !field.StructField.IsExported() {
+				continue
+			}
+			label, ignore := vingo.DiffLabel(field.StructField)
+			if ignore {
+				continue
+			}
+			value, _ := field.ValueOf(db.Statement.Context, row)
+			changes = append(changes, Change{Column: field.DBName, Label: label, Old: value})
+		}
+		key, _ := sch.PrioritizedPrimaryField.ValueOf(db.Statement.Context, row)
+		records = append(records, s.record(db, ActionDelete, fmt.Sprint(key), changes, "删除数据"))
+	}
+	s.write(db, records)
+}
+
+// 取出变更前数据，更新/删除失败或未影响行时返回false
+func snapshot(db *gorm.DB) (reflect.Value, bool) {
+	value, ok := db.InstanceGet(snapshotKey)
+	if !ok || db.Error != nil || db.RowsAffected == 0 {
+		return reflect.Value{}, false
+	}
+	return value.(reflect.Value), true
+}
+
+// 按条件查询模型数据，使用原语句的连接（事务中读取事务内数据）
+func (s *Plugin) find(db *gorm.DB, exprs []clause.Expression, unscoped bool) (reflect.Value, error) {
+	var stmt = db.Statement
+	var rows = reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	var tx = db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	// 隔离条件已包含在原语句条件中，读取主库避免从库延迟
+	tx = resolver.Primary(tenant.Unscoped(tx)).Model(reflect.New(stmt.Schema.ModelType).Interface())
+	if stmt.Table != "" {
+		tx = tx.Table(stmt.Table)
+	}
+	if unscoped {
+		tx = tx.Unscoped()
+	}
+	err := tx.Clauses(clause.Where{Exprs: exprs}).Limit(s.Config.MaxRows + 1).Find(rows.Interface()).Error
+	return rows.Elem(), err
+}
+
+func (s *Plugin) record(db *gorm.DB, action string, key string, changes Changes, content string) Record {
+	var record = Record{
+		Table:      db.Statement.Table,
+		PrimaryKey: key,
+		Action:     action,
+		Changes:    changes,
+		Content:    content,
+		CreatedAt:  vingo.NewLocalTime(time.Now()),
+	}
+	if actor, ok := ActorFromContext(db.Statement.Context); ok {
+		record.UserId = actor.UserId
+		record.AccId = actor.AccId
+		record.OrgId = actor.OrgId
+		record.RealName = actor.RealName
+		record.Ip = actor.Ip
+	}
+	return record
+}
+
+// 写入审计记录，与原语句使用同一连接，事务回滚时审计记录一并回滚
+func (s *Plugin) write(db *gorm.DB, records []Record) {
+	if len(records) == 0 {
+		return
+	}
+	var tx = db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	if err := resolver.Primary(tx).Table(s.Config.Table).Create(&records).Error; err != nil {
+		if s.Config.Strict {
+			_ = db.AddError(err)
+			return
+		}
+		vingo.LogError(fmt.Sprintf("[审计]写入审计记录错误：%v", err.Error()))
+	}
+}
+
+// 原语句的更新/删除条件：where条件和模型主键
+func conditions(stmt *gorm.Statement) []clause.Expression {
+	var exprs = make([]clause.Expression, 0, 2)
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			exprs = append(exprs, where.Exprs...)
+		}
+	}
+	var pk = stmt.Schema.PrioritizedPrimaryField
+	var values = make([]any, 0)
+	var value = reflect.Indirect(stmt.ReflectValue)
+	switch value.Kind() {
+	case reflect.Struct:
+		if v, zero := pk.ValueOf(stmt.Context, value); !zero {
+			values = append(values, v)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if v, zero := pk.ValueOf(stmt.Context, reflect.Indirect(value.Index(i))); !zero {
+				values = append(values, v)
+			}
+		}
+	}
+	if len(values) > 0 {
+		exprs = append(exprs, clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: values})
+	}
+	return exprs
+}
@@ -0,0 +1,122 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据库字典，支持html、markdown、json、xlsx格式输出
+// *****************************************************************************
+
+package book
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/db/schema"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 输出格式
+const (
+	FormatHtml     = "html"
+	FormatMarkdown = "md"
+	FormatJson     = "json"
+	FormatXlsx     = "xlsx"
+)
+
+var Formats = []string{FormatHtml, FormatMarkdown, FormatJson, FormatXlsx}
+
+type Database = schema.Database
+
+type Table = schema.Table
+
+type Column = schema.Column
+
+type Index = schema.Index
+
+type ForeignKey = schema.ForeignKey
+
+// 解析输出格式，格式：html,md,json,xlsx，为空时默认html
+func ParseFormats(value string) ([]string, error) {
+	var formats = make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch item {
+		case "":
+			continue
+		case "markdown":
+			item = FormatMarkdown
+		case "excel":
+			item = FormatXlsx
+		}
+		if !slices.Contains(Formats, item) {
+			return nil, fmt.Errorf("不支持的数据字典格式：%v，可选：%v", item, strings.Join(Formats, ","))
+		}
+		if !slices.Contains(formats, item) {
+			formats = append(formats, item)
+		}
+	}
+	if len(formats) == 0 {
+		formats = append(formats, FormatHtml)
+	}
+	return formats, nil
+}
+
+// 输出数据库字典到dir目录，返回生成的文件路径
+// 文件名为：库名_日期.格式，markdown输出为同名目录，每张表一个文件
+func Write(database Database, dir string, formats ...string) ([]string, error) {
+	if len(formats) == 0 {
+		formats = []string{FormatHtml}
+	}
+	if database.ReleaseTime == "" {
+		database.ReleaseTime = time.Now().Format("2006年01月02日")
+	}
+	vingo.Mkdir(dir)
+	var name = filepath.Join(dir, fmt.Sprintf("%v_%v", database.Name, time.Now().Format("20060102")))
+	var files = make([]string, 0, len(formats))
+	for _, format := range formats {
+		var err error
+		var output = name + "." + format
+		switch format {
+		case FormatHtml:
+			err = WriteHtml(database, output)
+		case FormatMarkdown:
+			output = name
+			err = WriteMarkdown(database, output)
+		case FormatJson:
+			err = WriteJson(database, output)
+		case FormatXlsx:
+			err = WriteXlsx(database, output)
+		default:
+			err = fmt.Errorf("不支持的数据字典格式：%v", format)
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, output)
+	}
+	return files, nil
+}
+
+// 输出json格式，包含索引和外键
+func WriteJson(database Database, filePath string) error {
+	// 空列表输出[]而不是null，便于程序读取
+	var tables = make([]Table, len(database.Tables))
+	for i, table := range database.Tables {
+		if table.Indexes == nil {
+			table.Indexes = []Index{}
+		}
+		if table.ForeignKeys == nil {
+			table.ForeignKeys = []ForeignKey{}
+		}
+		tables[i] = table
+	}
+	database.Tables = tables
+	content, err := json.MarshalIndent(database, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, content, 0644)
+}
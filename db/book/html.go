@@ -0,0 +1,134 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据库字典html格式
+// *****************************************************************************
+
+package book
+
+import (
+	"os"
+	"text/template"
+)
+
+const booktpl = `
+<!DOCTYPE html>
+<html>
+<head>
+  <title>{{ .Name }} 数据字典</title>
+  <style>
+    body {
+      margin: 0 50px;
+      font-size: 14px;
+      padding-bottom: 50px;
+    }
+
+    table {
+      border-collapse: collapse;
+      width: 100%;
+    }
+
+    th,
+    td {
+      border: 1px solid #ddd;
+      padding: 8px;
+      text-align: left;
+    }
+
+    th {
+      background-color: #f2f2f2;
+    }
+
+    .main {
+      display: flex;
+      height: 85vh;
+    }
+
+    .menu {
+      margin-right: 50px;
+      height: 100%;
+      overflow: auto;
+    }
+
+    .menu a {
+      display: flex;
+      color: #2196f3;
+      font-size: 12px;
+      text-decoration: inherit;
+    }
+
+    .menu a div:nth-child(1) {
+      flex: 1;
+    }
+
+    .menu a div:nth-child(2) {
+      color: #ccc;
+      margin: 0 10px;
+    }
+
+    .table {
+      flex: 1;
+      height: 100%;
+      overflow: auto;
+    }
+  </style>
+</head>
+<body>
+  <h1>{{ .Name }} 数据字典<span style="float:right">{{ .ReleaseTime }}</span></h1>
+  <div class="main">
+	  <div class="menu">
+	  {{ range .Tables }}
+	  <a href="#{{ .Name }}">
+      	<div>{{ .Name }}</div>
+        <div>{{ .Comment }}</div>
+      </a>
+	  {{ end }}
+	  </div>
+
+  	  <div class="table">
+	  {{ range .Tables }}
+	  <h2 id="{{ .Name }}">{{ .Name }} {{ .Comment }}</h2>
+	
+	  <table>
+		<tr>
+		  <th>字段名</th>
+		  <th>数据类型</th>
+		  <th>允许空值</th>
+		  <th>键</th>
+		  <th>默认值</th>
+		  <th>备注</th>
+		</tr>
+		{{ range .Columns }}
+		<tr>
+		  <td>{{ .Field }}</td>
+		  <td>{{ .Type }}</td>
+		  <td>{{ .Null }}</td>
+		  <td>{{ .Key }}</td>
+		  <td>{{ .Default }}</td>
+		  <td>{{ .Comment }}</td>
+		</tr>
+		{{ end }}
+	  </table>
+	
+	  {{ end }}
+	  </div>
+  </div>
+</body>
+</html>
+`
+
+// 输出html格式
+func WriteHtml(database Database, filePath string) error {
+	t, err := template.New("tpl").Parse(booktpl)
+	if err != nil {
+		return err
+	}
+
+	outputFile, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	return t.Execute(outputFile, database)
+}
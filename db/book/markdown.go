@@ -0,0 +1,101 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据库字典markdown格式，目录README.md加每张表一个文件
+// *****************************************************************************
+
+package book
+
+import (
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var markdownReplacer = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+// 输出markdown格式到dir目录
+func WriteMarkdown(database Database, dir string) error {
+	vingo.Mkdir(dir)
+
+	var readme strings.Builder
+	fmt.Fprintf(&readme, "# %v 数据字典\n\n", database.Name)
+	fmt.Fprintf(&readme, "> 生成时间：%v\n\n", database.ReleaseTime)
+	readme.WriteString("| 表名 | 说明 |\n| --- | --- |\n")
+	for _, table := range database.Tables {
+		fmt.Fprintf(&readme, "| [%v](%v.md) | %v |\n", table.Name, table.Name, markdownCell(table.Comment))
+		if err := os.WriteFile(filepath.Join(dir, table.Name+".md"), []byte(markdownTable(table)), 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, "README.md"), []byte(readme.String()), 0644)
+}
+
+// 单张表的markdown内容
+func markdownTable(table Table) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %v\n\n", table.Name)
+	if table.Comment != "" {
+		fmt.Fprintf(&b, "%v\n\n", table.Comment)
+	}
+
+	b.WriteString("## 字段\n\n")
+	b.WriteString("| 字段名 | 数据类型 | 允许空值 | 键 | 默认值 | 扩展 | 备注 |\n| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, column := range table.Columns {
+		fmt.Fprintf(&b, "| %v | %v | %v | %v | %v | %v | %v |\n",
+			markdownCell(column.Field),
+			markdownCell(column.Type),
+			column.Null,
+			column.Key,
+			markdownCell(defaultText(column.Default)),
+			markdownCell(column.Extra),
+			markdownCell(column.Comment))
+	}
+
+	if len(table.Indexes) > 0 {
+		b.WriteString("\n## 索引\n\n| 索引名 | 字段 | 类型 |\n| --- | --- | --- |\n")
+		for _, index := range table.Indexes {
+			fmt.Fprintf(&b, "| %v | %v | %v |\n", markdownCell(index.Name), markdownCell(strings.Join(index.Columns, ",")), indexType(index))
+		}
+	}
+
+	if len(table.ForeignKeys) > 0 {
+		b.WriteString("\n## 外键\n\n| 外键名 | 字段 | 关联表 | 关联字段 | 更新 | 删除 |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, fk := range table.ForeignKeys {
+			fmt.Fprintf(&b, "| %v | %v | [%v](%v.md) | %v | %v | %v |\n",
+				markdownCell(fk.Name),
+				markdownCell(strings.Join(fk.Columns, ",")),
+				fk.RefTable,
+				fk.RefTable,
+				markdownCell(strings.Join(fk.RefColumns, ",")),
+				fk.OnUpdate,
+				fk.OnDelete)
+		}
+	}
+	return b.String()
+}
+
+func markdownCell(value string) string {
+	return markdownReplacer.Replace(value)
+}
+
+// 默认值展示文本，无默认值时为空
+func defaultText(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func indexType(index Index) string {
+	switch {
+	case index.Primary:
+		return "PRIMARY"
+	case index.Unique:
+		return "UNIQUE"
+	default:
+		return "INDEX"
+	}
+}
@@ -0,0 +1,156 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据库字典xlsx格式，第一个sheet为目录，每张表一个sheet
+// *****************************************************************************
+
+package book
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"strings"
+	"unicode/utf8"
+)
+
+const indexSheet = "目录"
+
+// sheet名称不允许的字符
+var sheetNameReplacer = strings.NewReplacer(":", "_", "\\", "_", "/", "_", "?", "_", "*", "_", "[", "_", "]", "_")
+
+// 输出xlsx格式
+func WriteXlsx(database Database, filePath string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	header, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"F2F2F2"}},
+	})
+	if err != nil {
+		return err
+	}
+	link, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "2196F3", Underline: "single"}})
+	if err != nil {
+		return err
+	}
+
+	if err = f.SetSheetName("Sheet1", indexSheet); err != nil {
+		return err
+	}
+	var w = xlsxWriter{file: f, header: header}
+	w.sheet = indexSheet
+	w.row(database.Name+" 数据字典", database.ReleaseTime)
+	w.headerRow("表名", "说明")
+	_ = f.SetColWidth(indexSheet, "A", "A", 30)
+	_ = f.SetColWidth(indexSheet, "B", "B", 50)
+
+	var names = sheetNames(database.Tables)
+	for i, table := range database.Tables {
+		var sheet = names[i]
+		if _, err = f.NewSheet(sheet); err != nil {
+			return err
+		}
+
+		// 目录链接到表sheet
+		w.sheet = indexSheet
+		var cell = w.row(table.Name, table.Comment)
+		if err = f.SetCellHyperLink(indexSheet, cell, fmt.Sprintf("'%v'!A1", sheet), "Location"); err != nil {
+			return err
+		}
+		_ = f.SetCellStyle(indexSheet, cell, cell, link)
+
+		if err = w.table(sheet, table); err != nil {
+			return err
+		}
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return f.SaveAs(filePath)
+}
+
+// 按行写入sheet
+type xlsxWriter struct {
+	file    *excelize.File
+	header  int
+	sheet   string
+	current map[string]int
+	err     error
+}
+
+// 写入一行，返回首个单元格坐标
+func (s *xlsxWriter) row(values ...any) string {
+	if s.current == nil {
+		s.current = make(map[string]int)
+	}
+	s.current[s.sheet]++
+	var cell, _ = excelize.CoordinatesToCellName(1, s.current[s.sheet])
+	if err := s.file.SetSheetRow(s.sheet, cell, &values); err != nil && s.err == nil {
+		s.err = err
+	}
+	return cell
+}
+
+// 写入表头行
+func (s *xlsxWriter) headerRow(values ...any) {
+	var start = s.row(values...)
+	var end, _ = excelize.CoordinatesToCellName(len(values), s.current[s.sheet])
+	_ = s.file.SetCellStyle(s.sheet, start, end, s.header)
+}
+
+// 空行
+func (s *xlsxWriter) blank() {
+	s.current[s.sheet]++
+}
+
+func (s *xlsxWriter) table(sheet string, table Table) error {
+	s.sheet = sheet
+	s.row(table.Name, table.Comment)
+	s.blank()
+	s.headerRow("字段名", "数据类型", "允许空值", "键", "默认值", "扩展", "备注")
+	for _, column := range table.Columns {
+		s.row(column.Field, column.Type, column.Null, column.Key, defaultText(column.Default), column.Extra, column.Comment)
+	}
+	if len(table.Indexes) > 0 {
+		s.blank()
+		s.headerRow("索引名", "字段", "类型")
+		for _, index := range table.Indexes {
+			s.row(index.Name, strings.Join(index.Columns, ","), indexType(index))
+		}
+	}
+	if len(table.ForeignKeys) > 0 {
+		s.blank()
+		s.headerRow("外键名", "字段", "关联表", "关联字段", "更新", "删除")
+		for _, fk := range table.ForeignKeys {
+			s.row(fk.Name, strings.Join(fk.Columns, ","), fk.RefTable, strings.Join(fk.RefColumns, ","), fk.OnUpdate, fk.OnDelete)
+		}
+	}
+	_ = s.file.SetColWidth(sheet, "A", "B", 24)
+	_ = s.file.SetColWidth(sheet, "C", "F", 14)
+	_ = s.file.SetColWidth(sheet, "G", "G", 40)
+	return s.err
+}
+
+// 生成sheet名称，最长31个字符且不能重复
+func sheetNames(tables []Table) []string {
+	var names = make([]string, len(tables))
+	var used = map[string]bool{indexSheet: true}
+	for i, table := range tables {
+		var name = truncate(sheetNameReplacer.Replace(table.Name), 31)
+		for n := 1; used[strings.ToLower(name)]; n++ {
+			var suffix = fmt.Sprintf("~%v", n)
+			name = truncate(sheetNameReplacer.Replace(table.Name), 31-len(suffix)) + suffix
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+func truncate(value string, size int) string {
+	if utf8.RuneCountInString(value) <= size {
+		return value
+	}
+	return string([]rune(value)[:size])
+}
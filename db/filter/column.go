@@ -0,0 +1,38 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"regexp"
+)
+
+var columnRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// 数据库字段名，格式为column或table.column，只能包含字母、数字和下划线
+// 查询条件中的字段名无法使用参数绑定，拼接到sql前必须校验
+type Column string
+
+// 校验字段名
+func (s Column) Check() error {
+	if !columnRegexp.MatchString(string(s)) {
+		return &vingo.ParamException{Field: "column", Message: fmt.Sprintf("字段名[%v]不合法", string(s))}
+	}
+	return nil
+}
+
+// 校验字段名，不合法时panic
+func (s Column) Must() Column {
+	if err := s.Check(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s Column) String() string {
+	return string(s)
+}
+
+// 校验字段名并返回，不合法时panic
+func MustColumn(name string) string {
+	return Column(name).Must().String()
+}
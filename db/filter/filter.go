@@ -0,0 +1,311 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：根据查询结构体的filter标签生成查询条件，mysql、pgsql通用
+// *****************************************************************************
+
+package filter
+
+import (
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 支持的条件类型
+//
+//	eq          column = ?（默认）
+//	ne          column <> ?
+//	gt|gte|lt|lte  column > ? ...
+//	like        column LIKE %v%，多个字段之间为OR
+//	likeRight   column LIKE v%，多个字段之间为OR
+//	in|notIn    column IN (?)，值可以是切片、vingo.IntString、vingo.TextString、vingo.BoolString或逗号分隔的字符串
+//	between     column BETWEEN ? AND ?，值可以是vingo.DateAt、vingo.DateRange、vingo.BetweenText、长度为2的数组或逗号分隔的字符串
+//	findInSet   逗号分隔字段包含值，值为多个时之间为OR
+//	path        column = v OR column LIKE v,%，用于按路径查询下级
+//
+// 示例：
+//
+//	type ArticleQuery struct {
+//		page.Query
+//		Keyword   string            `form:"keyword" filter:"like;column:name,description"`
+//		Status    vingo.IntString   `form:"status" filter:"in;column:status"`
+//		CreatedAt *string           `form:"createdAt" filter:"between;column:created_at"`
+//		CateId    *uint             `form:"cateId" filter:"eq"`
+//	}
+const (
+	OpEq        = "eq"
+	OpNe        = "ne"
+	OpGt        = "gt"
+	OpGte       = "gte"
+	OpLt        = "lt"
+	OpLte       = "lte"
+	OpLike      = "like"
+	OpLikeRight = "likeRight"
+	OpIn        = "in"
+	OpNotIn     = "notIn"
+	OpBetween   = "between"
+	OpFindInSet = "findInSet"
+	OpPath      = "path"
+)
+
+var compareOps = map[string]string{OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}
+
+type field struct {
+	index   []int
+	name    string
+	op      string
+	columns []string
+}
+
+var fieldCache = &sync.Map{}
+
+// 根据query的filter标签生成查询条件，值为空（nil指针、空字符串、空切片）的字段不生成条件
+func ApplyFilters(db *gorm.DB, query any) *gorm.DB {
+	valueOf := reflect.ValueOf(query)
+	for valueOf.Kind() == reflect.Ptr {
+		if valueOf.IsNil() {
+			return db
+		}
+		valueOf = valueOf.Elem()
+	}
+	if valueOf.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("ApplyFilters参数必须是结构体，当前为%v", valueOf.Kind()))
+	}
+	for _, item := range parseFields(db, valueOf.Type()) {
+		value, ok := fieldValue(valueOf, item.index)
+		if !ok {
+			continue
+		}
+		db = item.apply(db, value)
+	}
+	return db
+}
+
+// 解析结构体的filter标签，嵌入的结构体会递归解析
+func parseFields(db *gorm.DB, typeOf reflect.Type) []field {
+	if cache, ok := fieldCache.Load(typeOf); ok {
+		return cache.([]field)
+	}
+	var fields = make([]field, 0)
+	for i := 0; i < typeOf.NumField(); i++ {
+		structField := typeOf.Field(i)
+		tag, hasTag := structField.Tag.Lookup("filter")
+		if tag == "-" || !structField.IsExported() {
+			continue
+		}
+		if !hasTag {
+			embedType := structField.Type
+			if embedType.Kind() == reflect.Ptr {
+				embedType = embedType.Elem()
+			}
+			if structField.Anonymous && embedType.Kind() == reflect.Struct {
+				for _, item := range parseFields(db, embedType) {
+					item.index = append([]int{i}, item.index...)
+					fields = append(fields, item)
+				}
+			}
+			continue
+		}
+		fields = append(fields, parseTag(db, structField, i, tag))
+	}
+	fieldCache.Store(typeOf, fields)
+	return fields
+}
+
+func parseTag(db *gorm.DB, structField reflect.StructField, index int, tag string) field {
+	var item = field{index: []int{index}, name: paramName(structField), op: OpEq}
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if key, value, found := strings.Cut(part, ":"); found {
+			if strings.TrimSpace(key) != "column" {
+				panic(fmt.Sprintf("字段%v的filter标签[%v]不支持", structField.Name, part))
+			}
+			for _, column := range strings.Split(value, ",") {
+				if column = strings.TrimSpace(column); column != "" {
+					item.columns = append(item.columns, column)
+				}
+			}
+			continue
+		}
+		item.op = part
+	}
+	switch item.op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpLike, OpLikeRight, OpIn, OpNotIn, OpBetween, OpFindInSet, OpPath:
+	default:
+		panic(fmt.Sprintf("字段%v的filter条件[%v]不支持", structField.Name, item.op))
+	}
+	if len(item.columns) == 0 {
+		item.columns = []string{db.NamingStrategy.ColumnName("", structField.Name)}
+	}
+	if len(item.columns) > 1 && item.op != OpLike && item.op != OpLikeRight {
+		panic(fmt.Sprintf("字段%v的filter条件[%v]只能指定一个字段", structField.Name, item.op))
+	}
+	return item
+}
+
+// 请求参数名，用于错误提示
+func paramName(structField reflect.StructField) string {
+	for _, key := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(structField.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return structField.Name
+}
+
+// 读取字段值，空值返回false
+func fieldValue(valueOf reflect.Value, index []int) (any, bool) {
+	var value = valueOf
+	for _, i := range index {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return nil, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		if value.Len() == 0 {
+			return nil, false
+		}
+	case reflect.Slice, reflect.Map:
+		if value.Len() == 0 {
+			return nil, false
+		}
+	}
+	return value.Interface(), true
+}
+
+func (s field) apply(db *gorm.DB, value any) *gorm.DB {
+	var column = db.Statement.Quote(s.columns[0])
+	switch s.op {
+	case OpLike, OpLikeRight:
+		var keyword = strings.Trim(vingo.ToString(value), " ")
+		if keyword == "" {
+			return db
+		}
+		keyword = EscapeLike(keyword) + "%"
+		if s.op == OpLike {
+			keyword = "%" + keyword
+		}
+		var text = make([]string, 0, len(s.columns))
+		var args = make([]any, 0, len(s.columns))
+		for _, item := range s.columns {
+			text = append(text, fmt.Sprintf("%v LIKE ?", db.Statement.Quote(item)))
+			args = append(args, keyword)
+		}
+		return db.Where(strings.Join(text, " OR "), args...)
+	case OpIn, OpNotIn:
+		var values = toSlice(value)
+		if len(values) == 0 {
+			return db
+		}
+		return db.Where(fmt.Sprintf("%v %v (?)", column, vingo.SY(s.op == OpIn, "IN", "NOT IN")), values)
+	case OpBetween:
+		start, end := toBetween(s.name, value)
+		return db.Where(fmt.Sprintf("%v BETWEEN ? AND ?", column), start, end)
+	case OpFindInSet:
+		var values = toSlice(value)
+		if len(values) == 0 {
+			return db
+		}
+		var text = make([]string, 0, len(values))
+		var args = make([]any, 0, len(values))
+		for _, item := range values {
+			text = append(text, findInSet(db, column))
+			args = append(args, vingo.ToString(item))
+		}
+		return db.Where(strings.Join(text, " OR "), args...)
+	case OpPath:
+		var path = vingo.ToString(value)
+		return db.Where(fmt.Sprintf("%v = ? OR %v LIKE ?", column, column), path, EscapeLike(path)+",%")
+	default:
+		return db.Where(fmt.Sprintf("%v %v ?", column, compareOps[s.op]), value)
+	}
+}
+
+// 逗号分隔字段包含值的条件，按数据库方言生成
+func findInSet(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "postgres" {
+		return fmt.Sprintf("? = ANY(string_to_array(%v, ','))", column)
+	}
+	return fmt.Sprintf("FIND_IN_SET(?, %v)", column)
+}
+
+// 转义LIKE通配符，避免关键词中的%和_匹配任意字符
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func toSlice(value any) []any {
+	var values = make([]any, 0)
+	switch v := value.(type) {
+	case vingo.IntString:
+		for _, item := range v.ToSlice() {
+			values = append(values, item)
+		}
+		return values
+	case vingo.TextString:
+		for _, item := range v.ToSlice() {
+			values = append(values, item)
+		}
+		return values
+	case vingo.BoolString:
+		for _, item := range v.ToSlice() {
+			values = append(values, item)
+		}
+		return values
+	}
+	valueOf := reflect.ValueOf(value)
+	switch valueOf.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < valueOf.Len(); i++ {
+			values = append(values, valueOf.Index(i).Interface())
+		}
+	case reflect.String:
+		for _, item := range strings.Split(valueOf.String(), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	default:
+		values = append(values, value)
+	}
+	return values
+}
+
+func toBetween(name string, value any) (any, any) {
+	switch v := value.(type) {
+	case vingo.DateAt:
+		return v.Start(), v.End()
+	case vingo.DateRange:
+		return v.Start.Format(vingo.DatetimeFormat), v.End.Format(vingo.DatetimeFormat)
+	case vingo.BetweenText:
+		between := v.ToStruct()
+		return between.Start, between.End
+	case [2]time.Time:
+		return v[0], v[1]
+	}
+	values := toSlice(value)
+	if len(values) != 2 {
+		panic(&vingo.ParamException{Field: name, Message: fmt.Sprintf("参数%v范围格式错误", name)})
+	}
+	return values[0], values[1]
+}
@@ -0,0 +1,430 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据库版本迁移，支持Go函数和.sql文件，迁移记录保存在schema_migrations表中
+// *****************************************************************************
+
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 迁移版本，Version建议使用时间戳（如20261019103000），按字符串顺序执行
+type Migration struct {
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error // Go迁移函数，与UpSql二选一
+	Down    func(tx *gorm.DB) error // Go回滚函数，与DownSql二选一
+	UpSql   string                  // sql迁移语句，多条语句以行尾分号分隔，pgsql的$$函数体不拆分
+	DownSql string                  // sql回滚语句
+}
+
+// 迁移状态
+type Status struct {
+	Version   string
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Missing   bool // 数据库中有记录，但代码中不存在该迁移
+}
+
+type Option struct {
+	Table  string    // 迁移记录表，默认schema_migrations
+	FS     fs.FS     // sql迁移文件，通常为embed.FS，文件名格式：{version}_{name}.up.sql、{version}_{name}.down.sql
+	Dir    string    // sql迁移文件所在目录，默认migrations
+	DryRun bool      // 只输出将要执行的sql，不执行，也不创建迁移记录表
+	Output io.Writer // 日志和dry-run输出，默认os.Stdout
+}
+
+// 迁移记录
+type schemaMigration struct {
+	Version   string    `gorm:"primaryKey;size:64"`
+	Name      string    `gorm:"size:255"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+var registry = make([]Migration, 0)
+
+// 注册Go迁移，通常在迁移文件的init中调用
+func Register(migrations ...Migration) {
+	registry = append(registry, migrations...)
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	option     Option
+	migrations []Migration
+}
+
+// 创建迁移器，包含已注册的Go迁移和option.FS中的sql迁移
+func New(db *gorm.DB, option Option) (*Migrator, error) {
+	if option.Table == "" {
+		option.Table = "schema_migrations"
+	}
+	if option.Dir == "" {
+		option.Dir = "migrations"
+	}
+	if option.Output == nil {
+		option.Output = os.Stdout
+	}
+	var s = &Migrator{db: db, option: option, migrations: append([]Migration{}, registry...)}
+	if option.FS != nil {
+		migrations, err := LoadSql(option.FS, option.Dir)
+		if err != nil {
+			return nil, err
+		}
+		s.migrations = append(s.migrations, migrations...)
+	}
+	var versions = make(map[string]bool)
+	for _, item := range s.migrations {
+		if versions[item.Version] {
+			return nil, fmt.Errorf("迁移版本%v重复", item.Version)
+		}
+		versions[item.Version] = true
+	}
+	sort.Slice(s.migrations, func(i, j int) bool {
+		return s.migrations[i].Version < s.migrations[j].Version
+	})
+	return s, nil
+}
+
+// 添加迁移
+func (s *Migrator) Add(migrations ...Migration) {
+	s.migrations = append(s.migrations, migrations...)
+	sort.Slice(s.migrations, func(i, j int) bool {
+		return s.migrations[i].Version < s.migrations[j].Version
+	})
+}
+
+// 执行所有未执行的迁移，返回本次执行的迁移
+func (s *Migrator) Up() (result []Migration, err error) {
+	err = s.locked(func(db *gorm.DB) error {
+		applied, err := s.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, item := range s.migrations {
+			if _, ok := applied[item.Version]; ok {
+				continue
+			}
+			if err = s.run(db, item, true); err != nil {
+				return err
+			}
+			result = append(result, item)
+		}
+		return nil
+	})
+	return
+}
+
+// 回滚最近执行的steps个迁移，steps小于等于0时回滚1个
+func (s *Migrator) Down(steps int) (result []Migration, err error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	err = s.locked(func(db *gorm.DB) error {
+		applied, err := s.applied(db)
+		if err != nil {
+			return err
+		}
+		var migrations = make(map[string]Migration)
+		for _, item := range s.migrations {
+			migrations[item.Version] = item
+		}
+		var versions = make([]string, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+		for _, version := range versions[:min(steps, len(versions))] {
+			item, ok := migrations[version]
+			if !ok {
+				return fmt.Errorf("迁移%v不存在，无法回滚", version)
+			}
+			if err = s.run(db, item, false); err != nil {
+				return err
+			}
+			result = append(result, item)
+		}
+		return nil
+	})
+	return
+}
+
+// 获取所有迁移的执行状态
+func (s *Migrator) Status() ([]Status, error) {
+	if err := s.ensureTable(s.db); err != nil {
+		return nil, err
+	}
+	applied, err := s.applied(s.db)
+	if err != nil {
+		return nil, err
+	}
+	var result = make([]Status, 0, len(s.migrations))
+	for _, item := range s.migrations {
+		var status = Status{Version: item.Version, Name: item.Name}
+		if record, ok := applied[item.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, item.Version)
+		}
+		result = append(result, status)
+	}
+	for _, record := range applied {
+		result = append(result, Status{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: &record.AppliedAt, Missing: true})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// 执行单个迁移并更新迁移记录，每个迁移在独立事务中执行
+// 注意：mysql的DDL语句会隐式提交事务，失败时需手动处理已执行的部分
+func (s *Migrator) run(db *gorm.DB, item Migration, up bool) error {
+	var handle = item.Down
+	var text = item.DownSql
+	if up {
+		handle = item.Up
+		text = item.UpSql
+	}
+	if handle == nil && strings.TrimSpace(text) == "" {
+		if !up {
+			return fmt.Errorf("迁移%v_%v没有回滚语句", item.Version, item.Name)
+		}
+		return fmt.Errorf("迁移%v_%v没有迁移语句", item.Version, item.Name)
+	}
+
+	s.logf("-- %v %v_%v\n", vingo.SY(up, "up", "down"), item.Version, item.Name)
+	var body = func(tx *gorm.DB) error {
+		if handle != nil {
+			if err := handle(tx); err != nil {
+				return fmt.Errorf("迁移%v_%v执行失败：%w", item.Version, item.Name, err)
+			}
+		} else {
+			for _, statement := range SplitSql(text) {
+				if err := tx.Exec(statement).Error; err != nil {
+					return fmt.Errorf("迁移%v_%v执行失败：%w", item.Version, item.Name, err)
+				}
+			}
+		}
+		var table = tx.Table(s.option.Table)
+		if up {
+			return table.Create(&schemaMigration{Version: item.Version, Name: item.Name, AppliedAt: time.Now()}).Error
+		}
+		return table.Where("version = ?", item.Version).Delete(&schemaMigration{}).Error
+	}
+	if s.option.DryRun {
+		return body(db.Session(&gorm.Session{DryRun: true, Logger: &dryRunLogger{output: s.option.Output}}))
+	}
+	return db.Transaction(body)
+}
+
+// 已执行的迁移，dry-run时迁移记录表不存在视为没有已执行的迁移
+func (s *Migrator) applied(db *gorm.DB) (map[string]schemaMigration, error) {
+	var records []schemaMigration
+	if s.option.DryRun && !db.Migrator().HasTable(s.option.Table) {
+		return map[string]schemaMigration{}, nil
+	}
+	if err := db.Table(s.option.Table).Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	var result = make(map[string]schemaMigration, len(records))
+	for _, item := range records {
+		result[item.Version] = item
+	}
+	return result, nil
+}
+
+// 创建迁移记录表，dry-run时不修改数据库
+func (s *Migrator) ensureTable(db *gorm.DB) error {
+	if s.option.DryRun {
+		return nil
+	}
+	return db.Table(s.option.Table).AutoMigrate(&schemaMigration{})
+}
+
+// 在同一个连接上加锁执行，保证多个副本同时启动时只有一个执行迁移
+func (s *Migrator) locked(handle func(db *gorm.DB) error) error {
+	return s.db.Connection(func(db *gorm.DB) error {
+		if err := s.ensureTable(db); err != nil {
+			return err
+		}
+		if s.option.DryRun {
+			return handle(db)
+		}
+		unlock, err := s.lock(db)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return handle(db)
+	})
+}
+
+// 数据库咨询锁，mysql使用GET_LOCK，pgsql使用pg_advisory_lock，其他数据库不加锁
+func (s *Migrator) lock(db *gorm.DB) (func(), error) {
+	var name = "migrate:" + s.option.Table
+	switch db.Dialector.Name() {
+	case "mysql":
+		var ok int
+		if err := db.Raw("SELECT GET_LOCK(?, ?)", name, 600).Scan(&ok).Error; err != nil {
+			return nil, err
+		}
+		if ok != 1 {
+			return nil, errors.New("获取迁移锁超时")
+		}
+		return func() {
+			db.Exec("SELECT RELEASE_LOCK(?)", name)
+		}, nil
+	case "postgres":
+		var hash = fnv.New64a()
+		_, _ = hash.Write([]byte(name))
+		var key = int64(hash.Sum64())
+		if err := db.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+			return nil, err
+		}
+		return func() {
+			db.Exec("SELECT pg_advisory_unlock(?)", key)
+		}, nil
+	}
+	return func() {}, nil
+}
+
+func (s *Migrator) logf(format string, args ...any) {
+	_, _ = fmt.Fprintf(s.option.Output, format, args...)
+}
+
+// dry-run时输出生成的sql
+type dryRunLogger struct {
+	output io.Writer
+}
+
+func (s *dryRunLogger) LogMode(logger.LogLevel) logger.Interface {
+	return s
+}
+
+func (s *dryRunLogger) Info(context.Context, string, ...any) {}
+
+func (s *dryRunLogger) Warn(context.Context, string, ...any) {}
+
+func (s *dryRunLogger) Error(context.Context, string, ...any) {}
+
+func (s *dryRunLogger) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	text, _ := fc()
+	_, _ = fmt.Fprintf(s.output, "%v;\n", strings.TrimSuffix(strings.TrimSpace(text), ";"))
+}
+
+var sqlFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// 读取目录中的sql迁移文件
+func LoadSql(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations = make(map[string]*Migration)
+	var versions = make([]string, 0)
+	for _, entry := range entries {
+		match := sqlFileRegexp.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		text, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		item, ok := migrations[match[1]]
+		if !ok {
+			item = &Migration{Version: match[1], Name: match[2]}
+			migrations[match[1]] = item
+			versions = append(versions, match[1])
+		} else if item.Name != match[2] {
+			return nil, fmt.Errorf("迁移版本%v的名称不一致：%v、%v", match[1], item.Name, match[2])
+		}
+		if match[3] == "up" {
+			item.UpSql = string(text)
+		} else {
+			item.DownSql = string(text)
+		}
+	}
+	var result = make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		result = append(result, *migrations[version])
+	}
+	return result, nil
+}
+
+// pgsql的美元引用标记，如$$、$body$
+var dollarQuote = regexp.MustCompile(`\$[A-Za-z_]*\$`)
+
+// 按行尾分号拆分多条sql语句，忽略空行和--注释行
+// pgsql函数、触发器等$$ ... $$包裹的内容不拆分
+func SplitSql(text string) []string {
+	var statements = make([]string, 0)
+	var current strings.Builder
+	var quote string // 当前所在的美元引用标记
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		var trimmed = strings.TrimSpace(line)
+		if quote == "" && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		for _, tag := range dollarQuote.FindAllString(line, -1) {
+			if quote == "" {
+				quote = tag
+			} else if tag == quote {
+				quote = ""
+			}
+		}
+		if quote == "" && strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		statements = append(statements, strings.TrimSpace(current.String()))
+	}
+	return statements
+}
+
+// 在dir目录中创建sql迁移文件，返回up、down文件路径
+func Create(dir string, name string) (string, string, error) {
+	if dir == "" {
+		dir = "migrations"
+	}
+	name = strings.ReplaceAll(strings.TrimSpace(name), " ", "_")
+	if name == "" {
+		return "", "", errors.New("迁移名称不能为空")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+	var version = time.Now().Format("20060102150405")
+	var up = path.Join(dir, fmt.Sprintf("%v_%v.up.sql", version, name))
+	var down = path.Join(dir, fmt.Sprintf("%v_%v.down.sql", version, name))
+	var header = fmt.Sprintf("-- %v %v\n-- 多条语句以行尾分号分隔\n\n", version, name)
+	if err := os.WriteFile(up, []byte(header), 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte(header), 0644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSql(t *testing.T) {
+	var cases = []struct {
+		name   string
+		text   string
+		expect []string
+	}{
+		{"多条语句", "-- 注释\nCREATE TABLE a (id int);\n\nINSERT INTO a VALUES (1);\r\nUPDATE a SET id = 2", []string{
+			"CREATE TABLE a (id int);",
+			"INSERT INTO a VALUES (1);",
+			"UPDATE a SET id = 2",
+		}},
+		{"跨行语句", "CREATE TABLE a (\n  id int\n);", []string{"CREATE TABLE a (\n  id int\n);"}},
+		{"函数体", "CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  -- 更新时间\n  NEW.updated_at = now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\nSELECT 1;", []string{
+			"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  -- 更新时间\n  NEW.updated_at = now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;",
+			"SELECT 1;",
+		}},
+		{"带标记的函数体", "DO $body$\nBEGIN\n  PERFORM '$$';\nEND;\n$body$;", []string{"DO $body$\nBEGIN\n  PERFORM '$$';\nEND;\n$body$;"}},
+		{"同一行", "SELECT $$a;b$$;\nSELECT 2;", []string{"SELECT $$a;b$$;", "SELECT 2;"}},
+	}
+	for _, c := range cases {
+		if result := SplitSql(c.text); !reflect.DeepEqual(result, c.expect) {
+			t.Errorf("%v：%q", c.name, result)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：批量插入与插入或更新（ON DUPLICATE KEY UPDATE），用于数据导入等大批量写入
+// *****************************************************************************
+
+package mysql
+
+import (
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/db/version"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"slices"
+)
+
+// 批量写入默认每批条数
+const DefaultBatchSize = 1000
+
+// 预处理语句的占位符上限，每批条数×字段数不能超过此值
+const maxPlaceholders = 65535
+
+type UpsertOption struct {
+	Columns   []string // 冲突判断字段，为空时使用主键；mysql按表的主键和唯一索引判断冲突，此处仅用于排除更新字段
+	Updates   []string // 冲突时更新的字段，为空时更新除主键、冲突字段和创建时间外的全部字段
+	BatchSize int      // 每批条数，默认DefaultBatchSize
+}
+
+// CreateInBatches 分批插入，value为切片指针，在事务中执行，任一批失败全部回滚，返回插入条数
+// 通过WithContext绑定外层事务时加入该事务执行
+func (s *DbApi) CreateInBatches(value any, batchSize ...int) (rows int64) {
+	s.Commit(func(tx *gorm.DB) {
+		rows = s.TxCreateInBatches(tx, value, batchSize...)
+	})
+	return
+}
+
+// TxCreateInBatches 在指定事务中分批插入
+func (s *DbApi) TxCreateInBatches(tx *gorm.DB, value any, batchSize ...int) int64 {
+	var size int
+	if len(batchSize) > 0 {
+		size = batchSize[0]
+	}
+	return tx.CreateInBatches(value, fitBatchSize(parseSchema(tx, value), size)).RowsAffected
+}
+
+// Upsert 分批插入，主键或唯一索引冲突时更新指定字段，在事务中执行，通过WithContext绑定外层事务时加入该事务执行
+// 返回mysql的影响行数：新插入计1，更新计2，数据未变化计0
+func (s *DbApi) Upsert(value any, option UpsertOption) (rows int64) {
+	s.Commit(func(tx *gorm.DB) {
+		rows = s.TxUpsert(tx, value, option)
+	})
+	return
+}
+
+// TxUpsert 在指定事务中插入或更新
+func (s *DbApi) TxUpsert(tx *gorm.DB, value any, option UpsertOption) int64 {
+	var sch = parseSchema(tx, value)
+	return tx.Clauses(upsertClause(sch, option)).CreateInBatches(value, fitBatchSize(sch, option.BatchSize)).RowsAffected
+}
+
+func parseSchema(tx *gorm.DB, value any) *schema.Schema {
+	var stmt = &gorm.Statement{DB: tx}
+	if err := stmt.Parse(value); err != nil {
+		panic(fmt.Sprintf("解析模型%T错误：%v", value, err))
+	}
+	return stmt.Schema
+}
+
+// 每批条数不超过占位符上限
+func fitBatchSize(sch *schema.Schema, size int) int {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if len(sch.DBNames) > 0 {
+		size = min(size, maxPlaceholders/len(sch.DBNames))
+	}
+	return size
+}
+
+// 冲突时以插入的值更新字段，模型有版本号字段时版本号自增
+func upsertClause(sch *schema.Schema, option UpsertOption) clause.OnConflict {
+	var conflict = dbNames(sch, option.Columns)
+	if len(conflict) == 0 {
+		conflict = sch.PrimaryFieldDBNames
+	}
+	var onConflict clause.OnConflict
+	for _, name := range conflict {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+
+	var updates = dbNames(sch, option.Updates)
+	if len(updates) == 0 {
+		for _, field := range sch.Fields {
+			if field.DBName != "" && field.Creatable && !field.PrimaryKey && field.AutoCreateTime == 0 && !slices.Contains(conflict, field.DBName) {
+				updates = append(updates, field.DBName)
+			}
+		}
+	}
+	var versionField = version.Field(sch)
+	for _, name := range updates {
+		if versionField == nil || name != versionField.DBName {
+			onConflict.DoUpdates = append(onConflict.DoUpdates, clause.AssignmentColumns([]string{name})...)
+		}
+	}
+	if len(onConflict.DoUpdates) == 0 {
+		onConflict.DoNothing = true
+	} else if versionField != nil {
+		var column = clause.Column{Table: clause.CurrentTable, Name: versionField.DBName}
+		onConflict.DoUpdates = append(onConflict.DoUpdates, clause.Assignment{Column: clause.Column{Name: versionField.DBName}, Value: gorm.Expr("? + 1", column)})
+	}
+	return onConflict
+}
+
+// 字段名转为数据库字段名，支持结构体字段名
+func dbNames(sch *schema.Schema, names []string) []string {
+	var items = make([]string, 0, len(names))
+	for _, name := range names {
+		if field := sch.LookUpField(name); field != nil && field.DBName != "" {
+			name = field.DBName
+		}
+		items = append(items, name)
+	}
+	return items
+}
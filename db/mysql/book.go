@@ -3,233 +3,130 @@ package mysql
 import (
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/lgdzz/vingo-utils-v2/vingo"
-	"os"
-	"path/filepath"
-	"text/template"
+	"github.com/lgdzz/vingo-utils-v2/db/book"
+	"github.com/lgdzz/vingo-utils-v2/db/schema"
 	"time"
 )
 
-type TableItem struct {
-	Name    string
-	Comment string
-	Columns []Column
-}
+type TableItem = book.Table
 
-type Database struct {
-	Name        string
-	ReleaseTime string
-	Tables      []TableItem
-}
+type Database = book.Database
 
-const booktpl = `
-<!DOCTYPE html>
-<html>
-<head>
-  <title>{{ .Name }} 数据字典</title>
-  <style>
-    body {
-      margin: 0 50px;
-      font-size: 14px;
-      padding-bottom: 50px;
-    }
-
-    table {
-      border-collapse: collapse;
-      width: 100%;
-    }
-
-    th,
-    td {
-      border: 1px solid #ddd;
-      padding: 8px;
-      text-align: left;
-    }
-
-    th {
-      background-color: #f2f2f2;
-    }
-
-    .main {
-      display: flex;
-      height: 85vh;
-    }
-
-    .menu {
-      margin-right: 50px;
-      height: 100%;
-      overflow: auto;
-    }
-
-    .menu a {
-      display: flex;
-      color: #2196f3;
-      font-size: 12px;
-      text-decoration: inherit;
-    }
-
-    .menu a div:nth-child(1) {
-      flex: 1;
-    }
-
-    .menu a div:nth-child(2) {
-      color: #ccc;
-      margin: 0 10px;
-    }
-
-    .table {
-      flex: 1;
-      height: 100%;
-      overflow: auto;
-    }
-  </style>
-</head>
-<body>
-  <h1>{{ .Name }} 数据字典<span style="float:right">{{ .ReleaseTime }}</span></h1>
-  <div class="main">
-	  <div class="menu">
-	  {{ range .Tables }}
-	  <a href="#{{ .Name }}">
-      	<div>{{ .Name }}</div>
-        <div>{{ .Comment }}</div>
-      </a>
-	  {{ end }}
-	  </div>
-
-  	  <div class="table">
-	  {{ range .Tables }}
-	  <h2 id="{{ .Name }}">{{ .Name }} {{ .Comment }}</h2>
-	
-	  <table>
-		<tr>
-		  <th>字段名</th>
-		  <th>数据类型</th>
-		  <th>允许空值</th>
-		  <th>键</th>
-		  <th>默认值</th>
-		  <th>备注</th>
-		</tr>
-		{{ range .Columns }}
-		<tr>
-		  <td>{{ .Field }}</td>
-		  <td>{{ .Type }}</td>
-		  <td>{{ .Null }}</td>
-		  <td>{{ .Key }}</td>
-		  <td>{{ .Default }}</td>
-		  <td>{{ .Comment }}</td>
-		</tr>
-		{{ end }}
-	  </table>
-	
-	  {{ end }}
-	  </div>
-  </div>
-</body>
-</html>
-`
-
-// 生成数据库字典
-func (s *DbApi) BuildBook() error {
-	var tables []TableItem
-	var dbName string
-	err := s.DB.Raw("SELECT DATABASE()").Row().Scan(&dbName)
+// 生成数据库字典，formats可选：html、md、json、xlsx，默认html
+func (s *DbApi) BuildBook(formats ...string) error {
+	database, err := s.Schema()
 	if err != nil {
 		return err
 	}
+	files, err := book.Write(database, "dbbook", formats...)
+	for _, file := range files {
+		fmt.Println("数据字典：", file)
+	}
+	return err
+}
 
-	vingo.Mkdir("dbbook")
-	var outputFilePath = filepath.Join(".", "dbbook", fmt.Sprintf("%v_%v.html", dbName, time.Now().Format("20060102")))
+// 读取数据库结构，包含字段、索引和外键，tables为空时读取全部表
+func (s *DbApi) Schema(tables ...string) (Database, error) {
+	var database = Database{Dialect: schema.DialectMysql, ReleaseTime: time.Now().Format("2006年01月02日")}
+	err := s.DB.Raw("SELECT DATABASE()").Row().Scan(&database.Name)
+	if err != nil {
+		return database, err
+	}
+	var dbName = database.Name
 
 	// 查询所有表的信息
-	rows, err := s.DB.Raw(`SELECT TABLE_NAME, TABLE_COMMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = ?`, dbName).Rows()
+	var query = s.DB.Table("information_schema.TABLES").Select("TABLE_NAME, TABLE_COMMENT").Where("TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'", dbName)
+	if len(tables) > 0 {
+		query = query.Where("TABLE_NAME IN ?", tables)
+	}
+	rows, err := query.Order("TABLE_NAME").Rows()
 	if err != nil {
-		return err
+		return database, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var tableName, tableComment string
-		if err := rows.Scan(&tableName, &tableComment); err != nil {
-			return err
+		var table TableItem
+		if err := rows.Scan(&table.Name, &table.Comment); err != nil {
+			return database, err
 		}
+		database.Tables = append(database.Tables, table)
+	}
+
+	for i := range database.Tables {
+		var table = &database.Tables[i]
 
 		// 查询每张表的列信息并按字段顺序排序
-		columns, err := s.getTableColumns(dbName, tableName)
+		columns, err := s.getTableColumns(dbName, table.Name)
 		if err != nil {
-			return err
+			return database, err
 		}
-
-		// 获取字段顺序
-		fieldOrder := s.getFieldOrder(dbName, tableName)
-
-		// 根据字段顺序排序
-		sortedColumns := make([]Column, len(columns))
-		for i, field := range fieldOrder {
-			for _, col := range columns {
-				if col.Field == field {
-					sortedColumns[i] = col
-					break
-				}
+		table.Columns = make([]schema.Column, 0, len(columns))
+		for _, col := range columns {
+			var item = schema.Column{Field: col.Field, Type: col.Type, Null: col.Null, Key: col.Key, Extra: col.Extra, Comment: col.Comment}
+			if col.Default.Valid {
+				item.Default = &col.Default.String
 			}
+			table.Columns = append(table.Columns, item)
 		}
 
-		tables = append(tables, TableItem{
-			Name:    tableName,
-			Comment: tableComment,
-			Columns: sortedColumns,
-		})
-	}
-
-	// 构造 Database 对象
-	database := Database{
-		Name:        dbName,
-		Tables:      tables,
-		ReleaseTime: time.Now().Format("2006年01月02日"),
-	}
-
-	// 渲染模板
-	t, err := template.New("tpl").Parse(booktpl)
-	if err != nil {
-		return err
+		if table.Indexes, err = s.getTableIndexes(dbName, table.Name); err != nil {
+			return database, err
+		}
+		if table.ForeignKeys, err = s.getTableForeignKeys(dbName, table.Name); err != nil {
+			return database, err
+		}
 	}
+	return database, nil
+}
 
-	outputFile, err := os.Create(outputFilePath)
+// 获取表索引
+func (s *DbApi) getTableIndexes(dbName string, tableName string) ([]schema.Index, error) {
+	var indexes []schema.Index
+	rows, err := s.DB.Raw(`SELECT INDEX_NAME, NON_UNIQUE, COLUMN_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX`, dbName, tableName).Rows()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer outputFile.Close()
+	defer rows.Close()
 
-	if err := t.Execute(outputFile, database); err != nil {
-		return err
+	for rows.Next() {
+		var name, column string
+		var nonUnique int
+		if err := rows.Scan(&name, &nonUnique, &column); err != nil {
+			return nil, err
+		}
+		indexes = schema.AppendIndex(indexes, name, column, nonUnique == 0, name == "PRIMARY")
 	}
-
-	return nil
+	return indexes, nil
 }
 
-// 获取字段顺序
-func (s *DbApi) getFieldOrder(dbName string, tableName string) []string {
-	var fields []string
-
-	rows, err := s.DB.Raw(`SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`, dbName, tableName).Rows()
+// 获取表外键
+func (s *DbApi) getTableForeignKeys(dbName string, tableName string) ([]schema.ForeignKey, error) {
+	var foreignKeys []schema.ForeignKey
+	rows, err := s.DB.Raw(`SELECT k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.UPDATE_RULE, r.DELETE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION`, dbName, tableName).Rows()
 	if err != nil {
-		return fields
+		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var field string
-		if err := rows.Scan(&field); err != nil {
-			continue
+		var name, column, refTable, refColumn, onUpdate, onDelete string
+		if err := rows.Scan(&name, &column, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			return nil, err
 		}
-		fields = append(fields, field)
+		foreignKeys = schema.AppendForeignKey(foreignKeys, name, column, refTable, refColumn, onUpdate, onDelete)
 	}
-
-	return fields
+	return foreignKeys, nil
 }
 
+// 获取表字段，按字段顺序排序
 func (s *DbApi) getTableColumns(dbName string, tableName string) ([]Column, error) {
 	var columns []Column
-	rows, err := s.DB.Raw(`SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, dbName, tableName).Rows()
+	rows, err := s.DB.Raw(`SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`, dbName, tableName).Rows()
 	if err != nil {
 		return nil, err
 	}
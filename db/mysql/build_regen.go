@@ -0,0 +1,350 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：模型重新生成，结构体写入xxx_gen.go并保留自定义代码，输出字段差异
+// *****************************************************************************
+
+package mysql
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 模型字段差异
+type ModelDiff struct {
+	Table   string
+	Added   []string // 新增字段
+	Removed []string // 删除字段
+	Changed []string // 类型或标签变更的字段，格式：字段: 原值 => 新值
+}
+
+// 是否有差异
+func (s ModelDiff) HasChange() bool {
+	return len(s.Added) > 0 || len(s.Removed) > 0 || len(s.Changed) > 0
+}
+
+// 输出差异报告
+func (s ModelDiff) Print() {
+	if !s.HasChange() {
+		fmt.Printf("[%v] 字段无变化\n", s.Table)
+		return
+	}
+	fmt.Printf("[%v] 字段变化：\n", s.Table)
+	for _, item := range s.Added {
+		fmt.Println("  + " + item)
+	}
+	for _, item := range s.Removed {
+		fmt.Println("  - " + item)
+	}
+	for _, item := range s.Changed {
+		fmt.Println("  ~ " + item)
+	}
+}
+
+// 模型字段信息
+type modelField struct {
+	Type string
+	Tag  string
+}
+
+// 重新生成模型，结构体写入model/表名_gen.go（覆盖），model/表名.go不存在时创建
+func regenerateModel(data TableData) (ModelDiff, error) {
+	var genPath = filepath.Join(".", "model", data.TableName+"_gen.go")
+	var userPath = filepath.Join(".", "model", data.TableName+".go")
+	var diff = ModelDiff{Table: data.TableName}
+
+	var oldPath = genPath
+	if !vingo.FileExists(genPath) {
+		oldPath = userPath
+	}
+	old, err := parseModelFields(oldPath, data.ModelName)
+	if err != nil {
+		return diff, err
+	}
+	diff = diffModelFields(data, old)
+
+	if err = writeFile(genPath, genTpl, data); err != nil {
+		return diff, err
+	}
+	// 旧版生成的单文件模型包含结构体，移到xxx_gen.go，避免重复定义
+	if oldPath == userPath && old != nil {
+		if err = removeModelStruct(userPath, data.ModelName); err != nil {
+			return diff, fmt.Errorf("[%v] 从%v中移除%v结构体失败：%w", data.TableName, userPath, data.ModelName, err)
+		}
+		fmt.Printf("[%v] 已将%v结构体从%v移至%v\n", data.TableName, data.ModelName, userPath, genPath)
+	}
+	if err = renderFile(userPath, userTpl, data); err != nil {
+		return diff, err
+	}
+	return diff, nil
+}
+
+// 从自定义代码文件中移除模型结构体及其TableName方法，保留其他代码，并清理不再使用的导入
+func removeModelStruct(filePath string, modelName string) error {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var fset = token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	var removed []ast.Node
+	var decls = make([]ast.Decl, 0, len(file.Decls))
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok == token.TYPE {
+				var specs = make([]ast.Spec, 0, len(d.Specs))
+				for _, spec := range d.Specs {
+					if spec.(*ast.TypeSpec).Name.Name == modelName {
+						removed = append(removed, spec)
+						continue
+					}
+					specs = append(specs, spec)
+				}
+				if len(specs) == 0 {
+					removed = append(removed, d)
+					continue
+				}
+				d.Specs = specs
+			}
+		case *ast.FuncDecl:
+			if d.Name.Name == "TableName" && receiverName(d) == modelName {
+				removed = append(removed, d)
+				continue
+			}
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+
+	// 移除被删除代码中的注释，否则会残留在文件中
+	var comments = make([]*ast.CommentGroup, 0, len(file.Comments))
+	for _, group := range file.Comments {
+		if !containsNode(removed, group) {
+			comments = append(comments, group)
+		}
+	}
+	file.Comments = comments
+	removeUnusedImports(file)
+
+	var buffer bytes.Buffer
+	if err = format.Node(&buffer, fset, file); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, buffer.Bytes(), 0644)
+}
+
+// 方法接收者的类型名
+func receiverName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	var expr = decl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// 注释是否位于被删除的代码内（含代码的文档注释）
+func containsNode(nodes []ast.Node, group *ast.CommentGroup) bool {
+	for _, node := range nodes {
+		var start = node.Pos()
+		switch n := node.(type) {
+		case *ast.GenDecl:
+			if n.Doc != nil {
+				start = n.Doc.Pos()
+			}
+		case *ast.TypeSpec:
+			if n.Doc != nil {
+				start = n.Doc.Pos()
+			}
+		case *ast.FuncDecl:
+			if n.Doc != nil {
+				start = n.Doc.Pos()
+			}
+		}
+		if group.Pos() >= start && group.End() <= node.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// 移除未使用的导入，导入名按别名或路径最后一段判断
+func removeUnusedImports(file *ast.File) {
+	var used = make(map[string]bool)
+	ast.Inspect(file, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	var decls = make([]ast.Decl, 0, len(file.Decls))
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			var specs = make([]ast.Spec, 0, len(d.Specs))
+			for _, spec := range d.Specs {
+				var item = spec.(*ast.ImportSpec)
+				importPath, _ := strconv.Unquote(item.Path.Value)
+				var name = path.Base(importPath)
+				if item.Name != nil {
+					name = item.Name.Name
+				}
+				if used[name] || name == "_" || name == "." {
+					specs = append(specs, spec)
+				}
+			}
+			if len(specs) == 0 {
+				continue
+			}
+			d.Specs = specs
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+	var imports = make([]*ast.ImportSpec, 0, len(file.Imports))
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			for _, spec := range d.Specs {
+				imports = append(imports, spec.(*ast.ImportSpec))
+			}
+		}
+	}
+	file.Imports = imports
+}
+
+// 解析模型文件中的结构体字段，按字段名（gorm column）索引，文件或结构体不存在时返回nil
+func parseModelFields(filePath string, modelName string) (map[string]modelField, error) {
+	if !vingo.FileExists(filePath) {
+		return nil, nil
+	}
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), filePath, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]modelField
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != modelName {
+			return true
+		}
+		structType, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return false
+		}
+		fields = make(map[string]modelField)
+		for _, field := range structType.Fields.List {
+			var tag string
+			if field.Tag != nil {
+				tag, _ = strconv.Unquote(field.Tag.Value)
+			}
+			var gormTag = reflect.StructTag(tag).Get("gorm")
+			var column = tagColumn(gormTag)
+			if column == "" {
+				continue
+			}
+			fields[column] = modelField{Type: types.ExprString(field.Type), Tag: gormTag}
+		}
+		return false
+	})
+	return fields, nil
+}
+
+// 从gorm标签中取字段名
+func tagColumn(tag string) string {
+	for _, item := range strings.Split(tag, ";") {
+		if strings.HasPrefix(item, "column:") {
+			return strings.TrimPrefix(item, "column:")
+		}
+	}
+	return ""
+}
+
+// 对比表结构与已生成的模型字段
+func diffModelFields(data TableData, old map[string]modelField) ModelDiff {
+	var diff = ModelDiff{Table: data.TableName}
+	if old == nil {
+		for _, column := range data.TableColumns {
+			diff.Added = append(diff.Added, column.Field+" "+column.DataType)
+		}
+		return diff
+	}
+	var exists = make(map[string]bool, len(data.TableColumns))
+	for _, column := range data.TableColumns {
+		exists[column.Field] = true
+		field, ok := old[column.Field]
+		if !ok {
+			diff.Added = append(diff.Added, column.Field+" "+column.DataType)
+			continue
+		}
+		if field.Type != column.DataType {
+			diff.Changed = append(diff.Changed, fmt.Sprintf("%v: %v => %v", column.Field, field.Type, column.DataType))
+		} else if field.Tag != column.GormTag {
+			diff.Changed = append(diff.Changed, fmt.Sprintf("%v: `%v` => `%v`", column.Field, field.Tag, column.GormTag))
+		}
+	}
+	for column, field := range old {
+		if !exists[column] {
+			diff.Removed = append(diff.Removed, column+" "+field.Type)
+		}
+	}
+	// map遍历无序，排序保证输出稳定
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// 按包含/排除模式过滤表名，模式语法同path.Match，如：sys_*
+func filterTables(tables []string, include []string, exclude []string) []string {
+	var result = make([]string, 0, len(tables))
+	for _, table := range tables {
+		if len(include) > 0 && !matchPatterns(table, include) {
+			continue
+		}
+		if matchPatterns(table, exclude) {
+			continue
+		}
+		result = append(result, table)
+	}
+	return result
+}
+
+func matchPatterns(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1,44 +1,45 @@
 package mysql
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
 	"github.com/duke-git/lancet/v2/strutil"
+	"github.com/lgdzz/vingo-utils-v2/db/schema"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"go/format"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"text/template"
 	"time"
 )
 
-const tpl = `// *****************************************************************************
+const tplHeader = `// *****************************************************************************
 // 作者: lgdz
 // 创建时间: {{ .Date }}
 // 描述：{{ .TableComment }}
 // *****************************************************************************
 
-package model
-
-import(
-	"github.com/lgdzz/vingo-utils-v2/vingo"
-	"github.com/lgdzz/vingo-utils-v2/db/page"
-	"github.com/lgdzz/vingo-utils-v2/db/mysql"
-	"gorm.io/gorm"
-)
+`
 
+const tplStruct = `
 type {{ .ModelName }} struct {
-	{{ range .TableColumns }}{{ .DataName }}   {{ .DataType }}  ` + "`gorm:\"{{ if eq .Key \"PRI\" }}primaryKey;{{ end }}column:{{ .Field }}\" json:\"{{ .JsonName }}\"`" + ` {{ if .Comment }}// {{ .Comment }}{{ end }}
+	{{ range .TableColumns }}{{ .DataName }}   {{ .DataType }}  ` + "`gorm:\"{{ .GormTag }}\" json:\"{{ .JsonName }}\"`" + ` {{ if .Comment }}// {{ .Comment }}{{ end }}
     {{ end }}
 }
 
 func (s *{{ .ModelName }}) TableName() string {
 	return "{{ .TableName }}"
 }
+`
 
+const tplQuery = `
 type {{ .ModelName }}Query struct {
 	mysql.PageQuery
-	CreatedAt *string ` + "`form:\"createdAt\"`" + `
-	Keyword string ` + "`form:\"keyword\"`" + `
+	CreatedAt *string ` + "`form:\"createdAt\"{{ if .HasCreatedAt }} filter:\"between;column:created_at\"{{ end }}`" + `
+	Keyword string ` + "`form:\"keyword\"{{ if .KeywordColumns }} filter:\"like;column:{{ .KeywordColumns }}\"{{ end }}`" + `
 }
 
 type {{ .ModelName }}Body struct {
@@ -46,12 +47,157 @@ type {{ .ModelName }}Body struct {
 }
 `
 
+// 单文件模型
+const tpl = tplHeader + `package model
+
+import(
+	{{ if .UseVingo }}"github.com/lgdzz/vingo-utils-v2/vingo"{{ end }}
+	"github.com/lgdzz/vingo-utils-v2/db/mysql"
+	{{ if .UseGorm }}"gorm.io/gorm"{{ end }}
+)
+` + tplStruct + tplQuery
+
+// 重新生成模式下的结构体文件（xxx_gen.go），每次生成都会覆盖
+const genTpl = `// Code generated by vingo CreateDbModel; DO NOT EDIT.
+// 表：{{ .TableName }} {{ .TableComment }}
+// 自定义方法请写在{{ .TableName }}.go中，重新生成时本文件会被覆盖
+
+package model
+{{ if or .UseVingo .UseGorm }}
+import(
+	{{ if .UseVingo }}"github.com/lgdzz/vingo-utils-v2/vingo"{{ end }}
+	{{ if .UseGorm }}"gorm.io/gorm"{{ end }}
+)
+{{ end }}` + tplStruct
+
+// 重新生成模式下的自定义代码文件，仅在不存在时创建
+const userTpl = tplHeader + `package model
+
+import(
+	"github.com/lgdzz/vingo-utils-v2/db/mysql"
+)
+` + tplQuery
+
+const serviceTpl = `// *****************************************************************************
+// 作者: lgdz
+// 创建时间: {{ .Date }}
+// 描述：{{ .TableComment }}
+// *****************************************************************************
+
+package service
+
+import (
+	"context"
+	"{{ .Module }}/model"
+	"github.com/lgdzz/vingo-utils-v2/db/mysql"
+	"github.com/lgdzz/vingo-utils-v2/db/page"
+	"github.com/lgdzz/vingo-utils-v2/db/version"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+)
+
+type {{ .ModelName }}Service struct {
+	DbApi *mysql.DbApi
+}
+
+func New{{ .ModelName }}Service(dbApi *mysql.DbApi) *{{ .ModelName }}Service {
+	return &{{ .ModelName }}Service{DbApi: dbApi}
+}
+
+// 绑定context，ctx中有进行中的事务时在该事务中执行：s.WithContext(tx.Statement.Context).Create(c, body)
+func (s *{{ .ModelName }}Service) WithContext(ctx context.Context) *{{ .ModelName }}Service {
+	return &{{ .ModelName }}Service{DbApi: s.DbApi.WithContext(ctx)}
+}
+
+// 创建
+func (s *{{ .ModelName }}Service) Create(c *vingo.Context, body *model.{{ .ModelName }}Body) model.{{ .ModelName }} {
+	var data = body.{{ .ModelName }}
+	s.DbApi.Create(&data)
+	return data
+}
+
+// 更新，模型有版本号字段（gorm:"version"）时必须提交版本号，数据已被修改时抛出vingo.ConflictException
+func (s *{{ .ModelName }}Service) Update(c *vingo.Context, body *model.{{ .ModelName }}Body) {
+	version.Require(s.DbApi.DB, &body.{{ .ModelName }})
+	var data = s.Detail(c, body.{{ .PrimaryName }})
+	s.DbApi.Model(&data).Omit("{{ .PrimaryKey }}").Updates(&body.{{ .ModelName }})
+}
+
+// 删除
+func (s *{{ .ModelName }}Service) Delete(c *vingo.Context, id any) {
+	var data = s.Detail(c, id)
+	s.DbApi.Delete(&data)
+}
+
+// 详情
+func (s *{{ .ModelName }}Service) Detail(c *vingo.Context, id any) model.{{ .ModelName }} {
+	return mysql.Fetch[model.{{ .ModelName }}](s.DbApi.DB, "{{ .PrimaryKey }} = ?", id)
+}
+
+// 分页列表
+func (s *{{ .ModelName }}Service) List(c *vingo.Context, query *model.{{ .ModelName }}Query) page.Result {
+	var db = s.DbApi.ApplyFilters(s.DbApi.Model(&model.{{ .ModelName }}{}), query)
+	return page.New[model.{{ .ModelName }}](db, page.Option{
+		Limit: query.Limit,
+		Order: page.OrderDefault(query.Order),
+	}, nil)
+}
+`
+
+const routerTpl = `// *****************************************************************************
+// 作者: lgdz
+// 创建时间: {{ .Date }}
+// 描述：{{ .TableComment }}
+// *****************************************************************************
+
+package router
+
+import (
+	"{{ .Module }}/model"
+	"{{ .Module }}/service"
+	"github.com/gin-gonic/gin"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+)
+
+func {{ .ModelName }}Router(g *gin.RouterGroup, s *service.{{ .ModelName }}Service) {
+	vingo.RoutesGet(g, "/{{ .RouteName }}/list", func(c *vingo.Context) {
+		var query = vingo.GetRequestQuery[model.{{ .ModelName }}Query](c)
+		c.ResponseBody(s.List(c, &query))
+	})
+	vingo.RoutesGet(g, "/{{ .RouteName }}/detail", func(c *vingo.Context) {
+		var query = vingo.GetRequestQuery[vingo.DetailQuery](c)
+		c.ResponseBody(s.Detail(c, query.Id))
+	})
+	vingo.RoutesPost(g, "/{{ .RouteName }}/create", func(c *vingo.Context) {
+		var body = vingo.GetRequestBody[model.{{ .ModelName }}Body](c)
+		c.ResponseBody(s.Create(c, &body))
+	})
+	vingo.RoutesPost(g, "/{{ .RouteName }}/update", func(c *vingo.Context) {
+		var body = vingo.GetRequestBody[model.{{ .ModelName }}Body](c)
+		s.Update(c, &body)
+		c.ResponseSuccess()
+	})
+	vingo.RoutesPost(g, "/{{ .RouteName }}/delete", func(c *vingo.Context) {
+		var body = vingo.GetRequestBody[vingo.IdBody](c)
+		s.Delete(c, body.Id)
+		c.ResponseSuccess()
+	})
+}
+`
+
 type TableData struct {
-	TableName    string
-	ModelName    string
-	TableComment string
-	TableColumns []Column
-	Date         string
+	TableName      string
+	ModelName      string
+	TableComment   string
+	TableColumns   []Column
+	Date           string
+	Module         string // 项目模块名称，service、router文件引用model使用
+	RouteName      string // 路由名称
+	PrimaryKey     string // 主键字段
+	PrimaryName    string // 主键属性名
+	HasCreatedAt   bool
+	KeywordColumns string // 关键词搜索字段
+	UseVingo       bool   // 模型是否引用vingo包
+	UseGorm        bool   // 模型是否引用gorm包
 }
 
 type Column struct {
@@ -65,9 +211,110 @@ type Column struct {
 	DataName string
 	DataType string
 	JsonName string
+	GormTag  string
+}
+
+// 生成模型选项
+type ModelOption struct {
+	Service    bool     // 同时生成service文件（service/表名.go）
+	Router     bool     // 同时生成路由文件（router/表名.go），需同时生成service
+	Regenerate bool     // 重新生成模式：结构体写入model/表名_gen.go并覆盖，model/表名.go仅在不存在时创建，用于保留自定义代码
+	Include    []string // 未指定表名时按模式匹配表名，如：sys_*，为空时匹配全部
+	Exclude    []string // 排除的表名模式
+}
+
+var columnSizeRegexp = regexp.MustCompile(`^\w+\((\d+)\)`)
+
+// 根据字段类型生成Go类型，可为NULL的字段使用指针
+func (s *Column) goType() string {
+	if s.Field == "deleted_at" {
+		return "gorm.DeletedAt"
+	}
+	var typ = strings.ToLower(s.Type)
+	var base = strings.TrimSpace(strings.SplitN(strings.SplitN(typ, "(", 2)[0], " ", 2)[0])
+	var unsigned = strings.Contains(typ, "unsigned")
+	var dataType string
+	switch base {
+	case "tinyint":
+		if strings.HasPrefix(typ, "tinyint(1)") {
+			dataType = "bool"
+		} else {
+			dataType = vingo.SY(unsigned, "uint", "int")
+		}
+	case "smallint", "mediumint", "int", "integer":
+		dataType = vingo.SY(unsigned, "uint", "int")
+	case "bigint":
+		dataType = vingo.SY(unsigned, "uint64", "int64")
+	case "decimal", "numeric":
+		// 定点数使用字符串，避免金额等字段转为浮点数丢失精度
+		dataType = "string"
+	case "float", "double", "real":
+		dataType = "float64"
+	case "bit":
+		dataType = "bool"
+	case "json":
+		dataType = "vingo.JsonObject[any]"
+	case "datetime", "timestamp":
+		// 时间类型始终使用指针，零值时输出null
+		return "*vingo.LocalTime"
+	case "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob":
+		return "[]byte"
+	default:
+		dataType = "string"
+	}
+	if s.Null == "YES" && s.Key != "PRI" {
+		return "*" + dataType
+	}
+	return dataType
+}
+
+// 根据字段属性生成gorm标签
+func (s *Column) gormTag() string {
+	var tags = make([]string, 0)
+	if s.Key == "PRI" {
+		tags = append(tags, "primaryKey")
+	}
+	if strings.Contains(strings.ToLower(s.Extra), "auto_increment") {
+		tags = append(tags, "autoIncrement")
+	}
+	tags = append(tags, "column:"+s.Field)
+
+	var typ = strings.ToLower(s.Type)
+	var decimal = strings.HasPrefix(typ, "decimal") || strings.HasPrefix(typ, "numeric")
+	if decimal {
+		// 保留精度定义，避免迁移时变成double
+		tags = append(tags, "type:"+typ)
+	} else if strings.HasPrefix(typ, "varchar") || strings.HasPrefix(typ, "char") {
+		if match := columnSizeRegexp.FindStringSubmatch(typ); match != nil {
+			tags = append(tags, "size:"+match[1])
+		}
+	}
+	if s.Null == "NO" && s.Key != "PRI" {
+		tags = append(tags, "not null")
+	}
+	if s.Default.Valid && s.Key != "PRI" && !strings.ContainsAny(s.Default.String, ";\"`") {
+		var value = s.Default.String
+		if !decimal && (s.goType() == "string" || s.goType() == "*string") {
+			value = "'" + value + "'"
+		}
+		tags = append(tags, "default:"+value)
+	}
+	switch s.Key {
+	case "UNI":
+		tags = append(tags, "uniqueIndex")
+	case "MUL":
+		tags = append(tags, "index")
+	}
+	return strings.Join(tags, ";")
 }
 
 func (s *DbApi) CreateDbModel(tableNames ...string) (bool, error) {
+	return s.CreateDbModelWithOption(ModelOption{}, tableNames...)
+}
+
+// 生成数据库模型，可选生成service和router文件，已存在的文件不会覆盖
+// 未指定表名时生成当前库中按Include/Exclude匹配的全部表；Regenerate模式下覆盖xxx_gen.go并输出字段差异
+func (s *DbApi) CreateDbModelWithOption(option ModelOption, tableNames ...string) (bool, error) {
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Println("请检查数据库是否正常连接")
@@ -75,60 +322,135 @@ func (s *DbApi) CreateDbModel(tableNames ...string) (bool, error) {
 		}
 	}()
 	vingo.Mkdir("model")
+	var module string
+	if option.Service || option.Router {
+		module = vingo.GetModuleName()
+	}
+	if len(tableNames) == 0 {
+		tableNames = s.matchTables(option.Include, option.Exclude)
+	}
 	for _, tableName := range tableNames {
+		var data = s.tableData(tableName, module)
 
-		var modelPath = filepath.Join(".", "model", tableName+".go")
-		// 模型文件存在则不创建
-		if vingo.FileExists(modelPath) {
-			continue
-		}
-
-		var tableComment string
-		s.DB.Table("information_schema.tables").Where("table_schema=? AND table_name=?", s.Config.Dbname, tableName).Select("table_comment").Scan(&tableComment)
-
-		var columns []Column
-		s.DB.Raw("SHOW FULL COLUMNS FROM " + tableName).Select("Field,Type,Comment").Scan(&columns)
-		columns = vingo.ForEach[Column](columns, func(item Column, index int) Column {
-			if vingo.StringStartsWith(item.Type, []string{"int", "tinyint", "smallint"}) {
-				item.DataType = "int"
-			} else if vingo.StringStartsWith(item.Type, []string{"decimal"}) {
-				item.DataType = "float64"
-			} else if item.Field == "deleted_at" {
-				item.DataType = "gorm.DeletedAt"
-			} else if vingo.StringStartsWith(item.Type, []string{"datetime"}) {
-				item.DataType = "*vingo.LocalTime"
-			} else {
-				item.DataType = "string"
+		if option.Regenerate {
+			diff, err := regenerateModel(data)
+			diff.Print()
+			if err != nil {
+				fmt.Println(err)
+				continue
 			}
-			item.JsonName = strutil.CamelCase(item.Field)
-			item.DataName = strutil.UpperFirst(item.JsonName)
-			return item
-		})
-
-		// 渲染模板
-		t, err := template.New("tpl").Parse(tpl)
-		if err != nil {
-			fmt.Println(err)
+		} else if err := renderFile(filepath.Join(".", "model", tableName+".go"), tpl, data); err != nil {
+			// 模型文件存在则不创建
 			return false, err
 		}
-
-		outputFile, err := os.Create(modelPath)
-		if err != nil {
-			fmt.Println(err)
-			return false, err
+		if option.Service || option.Router {
+			vingo.Mkdir("service")
+			if err := renderFile(filepath.Join(".", "service", tableName+".go"), serviceTpl, data); err != nil {
+				return false, err
+			}
 		}
-		defer outputFile.Close()
-
-		if err = t.Execute(outputFile, TableData{
-			TableName:    tableName,
-			ModelName:    strutil.UpperFirst(strutil.CamelCase(tableName)),
-			TableComment: tableComment,
-			TableColumns: columns,
-			Date:         time.Now().Format("2006/01/02"),
-		}); err != nil {
-			fmt.Println(err)
-			return false, err
+		if option.Router {
+			vingo.Mkdir("router")
+			if err := renderFile(filepath.Join(".", "router", tableName+".go"), routerTpl, data); err != nil {
+				return false, err
+			}
 		}
 	}
 	return true, nil
 }
+
+// 读取表结构，生成模板数据
+func (s *DbApi) tableData(tableName string, module string) TableData {
+	database, err := s.Schema(tableName)
+	if err != nil {
+		panic(err)
+	}
+	var table = database.Table(tableName)
+	if table == nil {
+		panic(fmt.Sprintf("表%v不存在", tableName))
+	}
+	var columns = schemaColumns(table.Columns)
+	var data = TableData{
+		TableName:    tableName,
+		ModelName:    strutil.UpperFirst(strutil.CamelCase(tableName)),
+		TableComment: table.Comment,
+		Date:         time.Now().Format("2006/01/02"),
+		Module:       module,
+		RouteName:    strutil.CamelCase(tableName),
+		PrimaryKey:   "id",
+		PrimaryName:  "Id",
+	}
+	var keywords = make([]string, 0)
+	data.TableColumns = vingo.ForEach[Column](columns, func(item Column, index int) Column {
+		item.DataType = item.goType()
+		item.GormTag = item.gormTag()
+		item.JsonName = strutil.CamelCase(item.Field)
+		item.DataName = strutil.UpperFirst(item.JsonName)
+		if item.Key == "PRI" {
+			data.PrimaryKey = item.Field
+			data.PrimaryName = item.DataName
+		}
+		data.UseVingo = data.UseVingo || strings.Contains(item.DataType, "vingo.")
+		data.UseGorm = data.UseGorm || strings.Contains(item.DataType, "gorm.")
+		if item.Field == "created_at" {
+			data.HasCreatedAt = true
+		}
+		if item.Field == "name" || item.Field == "title" {
+			keywords = append(keywords, item.Field)
+		}
+		return item
+	})
+	data.KeywordColumns = strings.Join(keywords, ",")
+	return data
+}
+
+// 转换为模型生成使用的字段
+func schemaColumns(columns []schema.Column) []Column {
+	var result = make([]Column, len(columns))
+	for i, item := range columns {
+		result[i] = Column{Field: item.Field, Type: item.Type, Null: item.Null, Key: item.Key, Extra: item.Extra, Comment: item.Comment}
+		if item.Default != nil {
+			result[i].Default = sql.NullString{String: *item.Default, Valid: true}
+		}
+	}
+	return result
+}
+
+// 获取当前库中匹配的表名
+func (s *DbApi) matchTables(include []string, exclude []string) []string {
+	var tables []string
+	s.DB.Table("information_schema.tables").Where("table_schema=? AND table_type='BASE TABLE'", s.Config.Dbname).Order("table_name").Pluck("table_name", &tables)
+	return filterTables(tables, include, exclude)
+}
+
+// 渲染模板到文件，文件已存在时跳过
+func renderFile(filePath string, text string, data TableData) error {
+	if vingo.FileExists(filePath) {
+		return nil
+	}
+	return writeFile(filePath, text, data)
+}
+
+// 渲染模板并覆盖写入文件
+func writeFile(filePath string, text string, data TableData) error {
+	t, err := template.New("tpl").Parse(text)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	var buffer bytes.Buffer
+	if err = t.Execute(&buffer, data); err != nil {
+		fmt.Println(err)
+		return err
+	}
+	// 格式化失败时保留原始内容，便于排查
+	content, err := format.Source(buffer.Bytes())
+	if err != nil {
+		content = buffer.Bytes()
+	}
+	if err = os.WriteFile(filePath, content, 0644); err != nil {
+		fmt.Println(err)
+		return err
+	}
+	return nil
+}
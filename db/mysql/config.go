@@ -6,7 +6,11 @@
 
 package mysql
 
-import "github.com/lgdzz/vingo-utils-v2/config"
+import (
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/config"
+	"github.com/lgdzz/vingo-utils-v2/db/resolver"
+)
 
 type Config struct {
 	config.Config
@@ -18,4 +22,20 @@ type Config struct {
 	Charset      string `yaml:"charset" json:"charset"`
 	MaxIdleConns int    `yaml:"maxIdleConns" json:"maxIdleConns"`
 	MaxOpenConns int    `yaml:"maxOpenConns" json:"maxOpenConns"`
+
+	Replicas       []resolver.ReplicaConfig `yaml:"replicas" json:"replicas"`             // 从库，配置后读请求按权重路由到从库
+	HealthInterval int                      `yaml:"healthInterval" json:"healthInterval"` // 从库健康检查间隔（秒），默认10
+
+	OptimisticLock bool `yaml:"optimisticLock" json:"optimisticLock"` // 注册乐观锁插件，仅对标记了gorm:"version"的字段生效
+}
+
+// 生成连接地址
+func (s *Config) Dsn(host string, port string, username string, password string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=true&loc=Local",
+		username,
+		password,
+		host,
+		port,
+		s.Dbname,
+		s.Charset)
 }
@@ -7,9 +7,17 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/lgdzz/vingo-utils-exception/exception"
+	"github.com/lgdzz/vingo-utils-v2/db/audit"
+	"github.com/lgdzz/vingo-utils-v2/db/filter"
+	"github.com/lgdzz/vingo-utils-v2/db/resolver"
+	"github.com/lgdzz/vingo-utils-v2/db/tenant"
+	"github.com/lgdzz/vingo-utils-v2/db/transaction"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
 	"gorm.io/gorm"
 	"reflect"
@@ -18,8 +26,9 @@ import (
 )
 
 type DbApi struct {
-	DB     *gorm.DB
-	Config Config
+	DB       *gorm.DB
+	Config   Config
+	Resolver *resolver.Resolver // 读写分离插件，未配置从库时为nil
 }
 
 func (s *DbApi) NewDB() *gorm.DB {
@@ -41,7 +50,7 @@ func (s *DbApi) FirstOrCreate(dest any, conds ...any) *gorm.DB {
 	return s.DB.FirstOrCreate(dest, conds...)
 }
 
-// Updates 更新指定模型字段
+// Updates 更新指定模型字段，开启OptimisticLock且模型包含版本号字段（gorm:"version"）时按版本号更新，数据已被修改时抛出vingo.ConflictException
 func (s *DbApi) Updates(model any, column string, columns ...any) *gorm.DB {
 	return s.DB.Select(column, columns...).Updates(model)
 }
@@ -51,6 +60,7 @@ func (s *DbApi) Delete(model any, conds ...any) *gorm.DB {
 	return s.DB.Delete(model, conds...)
 }
 
+// Save 保存数据记录，版本号校验同Updates
 func (s *DbApi) Save(value any) *gorm.DB {
 	return s.DB.Save(value)
 }
@@ -59,6 +69,21 @@ func (s *DbApi) Debug() *gorm.DB {
 	return s.DB.Debug()
 }
 
+// 携带当前请求数据范围的连接，需注册tenant插件
+func (s *DbApi) Scoped(c *vingo.Context) *gorm.DB {
+	return s.DB.WithContext(tenant.Context(c))
+}
+
+// 携带当前请求数据范围和操作人的连接，更新/删除时记录审计日志，需注册audit插件
+func (s *DbApi) Audited(c *vingo.Context) *gorm.DB {
+	return s.DB.WithContext(audit.WithActor(tenant.Context(c), audit.NewActor(c)))
+}
+
+// 强制使用主库，用于写后立即读的场景
+func (s *DbApi) Primary() *gorm.DB {
+	return resolver.Primary(s.DB)
+}
+
 func (s *DbApi) Unscoped() *gorm.DB {
 	return s.DB.Unscoped()
 }
@@ -105,7 +130,7 @@ func (s *DbApi) LikeOr(db *gorm.DB, keyword string, isRight bool, column ...stri
 	if keyword != "" {
 		var text []string
 		for _, item := range column {
-			text = append(text, fmt.Sprintf("%v like @text", item))
+			text = append(text, fmt.Sprintf("%v like @text", filter.MustColumn(item)))
 		}
 		var value string
 		if isRight {
@@ -120,10 +145,12 @@ func (s *DbApi) LikeOr(db *gorm.DB, keyword string, isRight bool, column ...stri
 
 // 时间范围查询
 func (s *DbApi) TimeBetween(db *gorm.DB, column string, dateAt vingo.DateAt) *gorm.DB {
+	column = filter.MustColumn(column)
 	return db.Where(fmt.Sprintf("%v BETWEEN ? AND ?", column), dateAt.Start(), dateAt.End())
 }
 
 func (s *DbApi) QueryWhere(db *gorm.DB, query any, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	valueOf := reflect.ValueOf(query)
 	typeOf := valueOf.Type()
 	if typeOf.Kind() == reflect.Ptr {
@@ -151,6 +178,7 @@ func (s *DbApi) QueryWhere(db *gorm.DB, query any, column string) *gorm.DB {
 
 // query参数必须是指针切片类型，如：*[]int|*[]uint|*[]string
 func (s *DbApi) QueryWhereIn(db *gorm.DB, query any, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != nil {
 		db = db.Where(fmt.Sprintf("%v in(?)", column), query)
 	}
@@ -158,6 +186,7 @@ func (s *DbApi) QueryWhereIn(db *gorm.DB, query any, column string) *gorm.DB {
 }
 
 func (s *DbApi) QueryWhereInString(db *gorm.DB, query vingo.TextString, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
 		db = db.Where(fmt.Sprintf("%v in(?)", column), query.ToSlice())
 	}
@@ -165,6 +194,7 @@ func (s *DbApi) QueryWhereInString(db *gorm.DB, query vingo.TextString, column s
 }
 
 func (s *DbApi) QueryWhereNotInString(db *gorm.DB, query vingo.TextString, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
 		db = db.Where(fmt.Sprintf("%v not in(?)", column), query.ToSlice())
 	}
@@ -172,6 +202,7 @@ func (s *DbApi) QueryWhereNotInString(db *gorm.DB, query vingo.TextString, colum
 }
 
 func (s *DbApi) QueryWhereInInt(db *gorm.DB, query vingo.IntString, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
 		db = db.Where(fmt.Sprintf("%v in(?)", column), query.ToSlice())
 	}
@@ -179,6 +210,7 @@ func (s *DbApi) QueryWhereInInt(db *gorm.DB, query vingo.IntString, column strin
 }
 
 func (s *DbApi) QueryWhereNotInInt(db *gorm.DB, query vingo.IntString, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
 		db = db.Where(fmt.Sprintf("%v not in(?)", column), query.ToSlice())
 	}
@@ -186,6 +218,7 @@ func (s *DbApi) QueryWhereNotInInt(db *gorm.DB, query vingo.IntString, column st
 }
 
 func (s *DbApi) QueryWhereDateAt(db *gorm.DB, query *vingo.DateAt, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != nil {
 		db = s.TimeBetween(db, column, *query)
 	}
@@ -193,19 +226,25 @@ func (s *DbApi) QueryWhereDateAt(db *gorm.DB, query *vingo.DateAt, column string
 }
 
 func (s *DbApi) QueryWhereDateAtString(db *gorm.DB, query *string, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != nil && *query != "" {
 		arr := strings.Split(*query, ",")
+		if len(arr) != 2 {
+			panic("时间范围字符串格式错误")
+		}
 		db = s.TimeBetween(db, column, vingo.DateAt{arr[0], arr[1]})
 	}
 	return db
 }
 
 func (s *DbApi) FindInSet(db *gorm.DB, query any, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	db = db.Where(fmt.Sprintf("FIND_IN_SET(?,%v)", column), query)
 	return db
 }
 
 func (s *DbApi) QueryWhereFindInSetInt(db *gorm.DB, query *int, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != nil {
 		db = s.FindInSet(db, *query, column)
 	}
@@ -213,6 +252,7 @@ func (s *DbApi) QueryWhereFindInSetInt(db *gorm.DB, query *int, column string) *
 }
 
 func (s *DbApi) QueryWhereFindInSetString(db *gorm.DB, query *string, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != nil {
 		db = s.FindInSet(db, *query, column)
 	}
@@ -220,34 +260,43 @@ func (s *DbApi) QueryWhereFindInSetString(db *gorm.DB, query *string, column str
 }
 
 func (s *DbApi) QueryWhereFindInSetInts(db *gorm.DB, query *[]uint, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != nil {
 		var text []string
+		var args []any
 		for _, value := range *query {
-			text = append(text, fmt.Sprintf("FIND_IN_SET(%d,%v)", value, column))
+			text = append(text, fmt.Sprintf("FIND_IN_SET(?,%v)", column))
+			args = append(args, value)
 		}
-		db = db.Where(strings.Join(text, " OR "))
+		db = db.Where(strings.Join(text, " OR "), args...)
 	}
 	return db
 }
 
 func (s *DbApi) QueryWhereFindInSetIntString(db *gorm.DB, query vingo.IntString, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
 		var text []string
+		var args []any
 		for _, value := range query.ToSlice() {
-			text = append(text, fmt.Sprintf("FIND_IN_SET(%d,%v)", value, column))
+			text = append(text, fmt.Sprintf("FIND_IN_SET(?,%v)", column))
+			args = append(args, value)
 		}
-		db = db.Where(strings.Join(text, " OR "))
+		db = db.Where(strings.Join(text, " OR "), args...)
 	}
 	return db
 }
 
 func (s *DbApi) QueryWhereFindInSetTextString(db *gorm.DB, query vingo.TextString, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
 		var text []string
+		var args []any
 		for _, value := range query.ToSlice() {
-			text = append(text, fmt.Sprintf("FIND_IN_SET('%v',%v)", value, column))
+			text = append(text, fmt.Sprintf("FIND_IN_SET(?,%v)", column))
+			args = append(args, value)
 		}
-		db = db.Where(strings.Join(text, " OR "))
+		db = db.Where(strings.Join(text, " OR "), args...)
 	}
 	return db
 }
@@ -267,13 +316,20 @@ func (s *DbApi) QueryWhereLikeRight(db *gorm.DB, query string, column ...string)
 }
 
 func (s *DbApi) QueryWherePath(db *gorm.DB, query string, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
-		db = db.Where(fmt.Sprintf("%v='%v' OR %v LIKE '%v,%%'", column, query, column, query))
+		db = db.Where(fmt.Sprintf("%v = ? OR %v LIKE ?", column, column), query, filter.EscapeLike(query)+",%")
 	}
 	return db
 }
 
+// 根据query结构体的filter标签生成查询条件，见filter.ApplyFilters
+func (s *DbApi) ApplyFilters(db *gorm.DB, query any) *gorm.DB {
+	return filter.ApplyFilters(db, query)
+}
+
 func (s *DbApi) QueryWhereBetween(db *gorm.DB, query *[2]any, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != nil {
 		db = db.Where(fmt.Sprintf("%v BETWEEN ? AND ?", column), query[0], query[1])
 	}
@@ -281,6 +337,7 @@ func (s *DbApi) QueryWhereBetween(db *gorm.DB, query *[2]any, column string) *go
 }
 
 func (s *DbApi) QueryWhereBetweenText(db *gorm.DB, query vingo.BetweenText, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	if query != "" {
 		between := query.ToStruct()
 		db = db.Where(fmt.Sprintf("%v BETWEEN ? AND ?", column), between.Start, between.End)
@@ -289,12 +346,14 @@ func (s *DbApi) QueryWhereBetweenText(db *gorm.DB, query vingo.BetweenText, colu
 }
 
 func (s *DbApi) QueryWhereDeletedAt(db *gorm.DB, column string) *gorm.DB {
+	column = filter.MustColumn(column)
 	db = db.Where(fmt.Sprintf("%v IS NULL", column))
 	return db
 }
 
 // 指定字段第一个汉字按A-Z排序
 func (s *DbApi) ChineseSortString(column string) string {
+	column = filter.MustColumn(column)
 	return fmt.Sprintf("CONVERT(SUBSTR(%v, 1, 1) USING gbk)", column)
 }
 
@@ -454,11 +513,28 @@ func SetPathAndChildPath[T any](model *T, option PathOption) {
 	SetPathChild[T](model, option)
 }
 
-// 事务函数
-func (s *DbApi) Commit(handler func(*gorm.DB)) {
-	tx := s.Begin()
-	defer s.AutoCommit(tx)
-	handler(tx)
+// 事务函数，s.DB的context中已有事务时以保存点嵌套执行，最外层事务提交后执行AfterCommit注册的函数
+// 在事务中调用时需使用WithContext绑定外层事务：s.DbApi.WithContext(tx.Statement.Context).Commit(...)，否则开启独立的新事务
+func (s *DbApi) Commit(handler func(*gorm.DB), option ...transaction.Option) {
+	transaction.Run(s.DB, handler, isRetryable, option...)
+}
+
+// CommitContext 加入ctx中的事务执行，ctx中没有事务时开启新事务
+// 嵌套调用时传入外层事务的context：s.DbApi.CommitContext(tx.Statement.Context, ...)
+func (s *DbApi) CommitContext(ctx context.Context, handler func(*gorm.DB), option ...transaction.Option) {
+	transaction.Run(s.DB.WithContext(ctx), handler, isRetryable, option...)
+}
+
+// WithContext 绑定context，ctx中有进行中的事务时后续操作均在该事务中执行
+func (s *DbApi) WithContext(ctx context.Context) *DbApi {
+	var api = *s
+	api.DB = transaction.DB(s.DB, ctx)
+	return &api
+}
+
+// AfterCommit 注册事务提交后执行的函数，tx不在事务中时立即执行
+func (s *DbApi) AfterCommit(tx *gorm.DB, fn func()) {
+	transaction.AfterCommit(tx, fn)
 }
 
 // 通过条件获取单条记录
@@ -486,3 +562,12 @@ func FetchById[T any](tx *gorm.DB, id any, diff ...bool) (row T) {
 	}
 	return
 }
+
+// 死锁时可重试事务
+func isRetryable(err error) bool {
+	var mysqlErr *mysqlDriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213
+	}
+	return strings.Contains(err.Error(), "Error 1213")
+}
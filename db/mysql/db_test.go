@@ -0,0 +1,198 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lgdzz/vingo-utils-v2/db/filter"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 注入用例，同时作为模糊测试的种子
+var injectionSeeds = []string{
+	"",
+	"1",
+	"1,2,3",
+	"a,b",
+	"2024-01-01 00:00:00,2024-12-31 23:59:59",
+	"' OR '1'='1",
+	"1' OR 1=1 -- ",
+	"1); DROP TABLE users; --",
+	`\'; SELECT SLEEP(5); #`,
+	"%",
+	"_",
+	"0,0/*",
+	"name`; DELETE FROM t; --",
+}
+
+type whereCase struct {
+	name  string
+	build func(s *DbApi, db *gorm.DB, value string, column string) *gorm.DB
+}
+
+// 覆盖全部QueryWhere*及其依赖的条件辅助方法
+var whereCases = []whereCase{
+	{"QueryWhere", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB { return s.QueryWhere(db, value, column) }},
+	{"QueryWherePtr", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB { return s.QueryWhere(db, &value, column) }},
+	{"QueryWhereIn", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereIn(db, &[]string{value}, column)
+	}},
+	{"QueryWhereInString", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereInString(db, vingo.TextString(value), column)
+	}},
+	{"QueryWhereNotInString", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereNotInString(db, vingo.TextString(value), column)
+	}},
+	{"QueryWhereInInt", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereInInt(db, vingo.IntString(value), column)
+	}},
+	{"QueryWhereNotInInt", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereNotInInt(db, vingo.IntString(value), column)
+	}},
+	{"TimeBetween", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.TimeBetween(db, column, vingo.DateAt{value, value})
+	}},
+	{"QueryWhereDateAt", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereDateAt(db, &vingo.DateAt{value, value}, column)
+	}},
+	{"QueryWhereDateAtString", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereDateAtString(db, &value, column)
+	}},
+	{"FindInSet", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB { return s.FindInSet(db, value, column) }},
+	{"QueryWhereFindInSetInt", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		var query = vingo.ToInt(value)
+		return s.QueryWhereFindInSetInt(db, &query, column)
+	}},
+	{"QueryWhereFindInSetString", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereFindInSetString(db, &value, column)
+	}},
+	{"QueryWhereFindInSetInts", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		var query = vingo.IntString(value)
+		var ids = query.ToUintSlice()
+		return s.QueryWhereFindInSetInts(db, &ids, column)
+	}},
+	{"QueryWhereFindInSetIntString", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereFindInSetIntString(db, vingo.IntString(value), column)
+	}},
+	{"QueryWhereFindInSetTextString", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereFindInSetTextString(db, vingo.TextString(value), column)
+	}},
+	{"QueryWhereLike", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereLike(db, value, column, column)
+	}},
+	{"QueryWhereLikeRight", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereLikeRight(db, value, column)
+	}},
+	{"QueryWherePath", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWherePath(db, value, column)
+	}},
+	{"QueryWhereBetween", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereBetween(db, &[2]any{value, value}, column)
+	}},
+	{"QueryWhereBetweenText", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereBetweenText(db, vingo.BetweenText(value), column)
+	}},
+	{"QueryWhereDeletedAt", func(s *DbApi, db *gorm.DB, value, column string) *gorm.DB {
+		return s.QueryWhereDeletedAt(db, column)
+	}},
+}
+
+func dryRunApi(t testing.TB) *DbApi {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "root:root@tcp(127.0.0.1:3306)/test", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DbApi{DB: db}
+}
+
+// 生成查询语句，返回语句、绑定参数和panic的值
+func render(s *DbApi, c whereCase, value string, column string) (sql string, vars []any, failure any) {
+	defer func() {
+		if r := recover(); r != nil {
+			failure = r
+		}
+	}()
+	var tx = c.build(s, s.DB.Table("t"), value, column).Find(&[]map[string]any{})
+	return tx.Statement.SQL.String(), tx.Statement.Vars, nil
+}
+
+// 业务校验抛出的异常（参数格式错误），其他panic视为缺陷
+func rejected(failure any) bool {
+	switch failure.(type) {
+	case string, *vingo.ParamException:
+		return true
+	}
+	return false
+}
+
+// 与value结构相同的安全值，逗号分隔的项数一致
+func shapeOf(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ',' {
+			return r
+		}
+		return 'x'
+	}, value)
+}
+
+// 用户值只能作为绑定参数：任意值生成的语句与同结构安全值生成的语句完全一致
+func FuzzQueryWhereValue(f *testing.F) {
+	for _, seed := range injectionSeeds {
+		f.Add(seed)
+	}
+	var s = dryRunApi(f)
+	f.Fuzz(func(t *testing.T, value string) {
+		for _, c := range whereCases {
+			sql, vars, failure := render(s, c, value, "name")
+			if failure != nil {
+				if !rejected(failure) {
+					t.Fatalf("%v(%q) panic: %v", c.name, value, failure)
+				}
+				continue
+			}
+			expect, _, failure := render(s, c, shapeOf(value), "name")
+			if failure != nil {
+				continue
+			}
+			if sql != expect {
+				t.Fatalf("%v(%q) 语句随参数变化：\n%v\n%v", c.name, value, sql, expect)
+			}
+			if c.name == "QueryWherePath" && value != "" && vars[1] != filter.EscapeLike(value)+",%" {
+				t.Fatalf("%v(%q) 未转义LIKE通配符：%v", c.name, value, vars[1])
+			}
+		}
+	})
+}
+
+// 字段名不合法时filter.MustColumn抛出参数异常，合法时原样出现在语句中
+func FuzzQueryWhereColumn(f *testing.F) {
+	for _, seed := range append(injectionSeeds, "name", "t.name", "user_id", "1name", "name;", "t.a.b") {
+		f.Add(seed)
+	}
+	var s = dryRunApi(f)
+	f.Fuzz(func(t *testing.T, column string) {
+		var valid = filter.Column(column).Check() == nil
+		for _, c := range whereCases {
+			sql, _, failure := render(s, c, "2024-01-01 00:00:00,2024-12-31 23:59:59", column)
+			if !valid {
+				if _, ok := failure.(*vingo.ParamException); !ok {
+					t.Fatalf("%v 字段名%q未被拦截：%v %v", c.name, column, sql, failure)
+				}
+				continue
+			}
+			if failure != nil {
+				t.Fatalf("%v 字段名%q panic: %v", c.name, column, failure)
+			}
+			if !strings.Contains(sql, column) {
+				t.Fatalf("%v 语句缺少字段%q：%v", c.name, column, sql)
+			}
+		}
+	})
+}
@@ -1,8 +1,11 @@
 package mysql
 
 import (
+	"errors"
 	"fmt"
 	"github.com/lgdzz/vingo-utils-exception/exception"
+	"github.com/lgdzz/vingo-utils-v2/db/resolver"
+	"github.com/lgdzz/vingo-utils-v2/db/version"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -26,13 +29,7 @@ func NewMysql(config Config) *DbApi {
 		Config: config,
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=true&loc=Local",
-		config.Username,
-		config.Password,
-		config.Host,
-		config.Port,
-		config.Dbname,
-		config.Charset)
+	dsn := config.Dsn(config.Host, config.Port, config.Username, config.Password)
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
 		SkipDefaultTransaction: true,
 		PrepareStmt:            true,
@@ -70,10 +67,55 @@ func NewMysql(config Config) *DbApi {
 	RegisterAfterUpdate(db)
 	RegisterAfterDelete(db)
 
+	// 注册乐观锁插件
+	if config.OptimisticLock {
+		if err = db.Use(version.New()); err != nil {
+			panic(fmt.Sprintf("插件注册失败: %v", err.Error()))
+		}
+	}
+
+	// 注册读写分离插件
+	if len(config.Replicas) > 0 {
+		dbApi.Resolver = resolver.New(openReplicas(config, db), resolver.Option{HealthInterval: time.Duration(config.HealthInterval) * time.Second})
+		if err = db.Use(dbApi.Resolver); err != nil {
+			panic(fmt.Sprintf("插件注册失败: %v", err.Error()))
+		}
+	}
+
 	dbApi.DB = db
 	return &dbApi
 }
 
+// 连接从库，从库连接池配置与主库一致
+func openReplicas(config Config, primary *gorm.DB) []*resolver.Replica {
+	var replicas = make([]*resolver.Replica, 0, len(config.Replicas))
+	for _, item := range config.Replicas {
+		config.StringValue(&item.Port, config.Port)
+		config.StringValue(&item.Username, config.Username)
+		config.StringValue(&item.Password, config.Password)
+		db, err := gorm.Open(mysql.Open(config.Dsn(item.Host, item.Port, item.Username, item.Password)), &gorm.Config{Logger: primary.Logger})
+		if err != nil {
+			panic("Error to Db replica connection, err: " + err.Error())
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+		sqlDB.SetConnMaxLifetime(60 * time.Minute)
+
+		var pool gorm.ConnPool = sqlDB
+		if primary.PrepareStmt {
+			pool = gorm.NewPreparedStmtDB(sqlDB)
+		}
+		replicas = append(replicas, &resolver.Replica{
+			Name:   item.Host + ":" + item.Port,
+			Weight: item.Weight,
+			Pool:   pool,
+			DB:     sqlDB,
+		})
+	}
+	return replicas
+}
+
 func RegisterAfterQuery(db *gorm.DB) {
 	err := db.Callback().Query().After("gorm:query").Register("gormerror:after_query", func(db *gorm.DB) {
 		if db.Error != nil && db.Error != gorm.ErrRecordNotFound {
@@ -98,6 +140,9 @@ func RegisterAfterCreate(db *gorm.DB) {
 
 func RegisterAfterUpdate(db *gorm.DB) {
 	err := db.Callback().Update().After("gorm:update").Register("gormerror:after_update", func(db *gorm.DB) {
+		if conflict := new(vingo.ConflictException); errors.As(db.Error, &conflict) {
+			panic(conflict)
+		}
 		if db.Error != nil {
 			panic(&exception.DbException{Message: db.Error.Error()})
 		}
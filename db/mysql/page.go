@@ -1,119 +1,34 @@
 package mysql
 
 import (
-	"fmt"
-	"github.com/lgdzz/vingo-utils-v2/vingo"
-	"gorm.io/gorm"
-	"strings"
+	"github.com/lgdzz/vingo-utils-v2/db/page"
 )
 
-type PageResult struct {
-	Page  int   `json:"page"`
-	Size  int   `json:"size"`
-	Total int64 `json:"total"` // 总的记录数
-	Items any   `json:"items"` // 查询数据列表
-}
-
-type PageLimit struct {
-	Page int `form:"page"`
-	Size int `form:"size"`
-}
-
-func (s *PageLimit) GetPage() int {
-	if s.Page > 0 {
-		return s.Page
-	} else {
-		return 1
-	}
-}
-
-func (s *PageLimit) GetSize() int {
-	if s.Size > 0 {
-		return s.Size
-	} else {
-		return 10
-	}
-}
+// 分页类型统一由page包实现，此处保留原有类型名称
+type PageResult = page.Result
 
-func (s *PageLimit) Offset() int64 {
-	if s.Page > 0 {
-		return int64((s.Page - 1) * s.Size)
-	} else {
-		return 0
-	}
-}
+type PageLimit = page.Limit
 
-type PageOrder struct {
-	Column string `form:"sortField"`
-	Sort   string `form:"sortOrder"`
-}
+type PageOrder = page.Order
 
-type PageQuery struct {
-	Limit PageLimit
-	Order *PageOrder
-}
+type PageQuery = page.Query
 
-func (s *PageOrder) HandleColumn() string {
-	var sort = strings.ToLower(s.Sort)
-	if sort != "asc" && sort != "desc" {
-		panic("存在sql注入的风险")
-	}
-	var items = strings.Split(s.Column, ".")
-	for index, item := range items {
-		items[index] = "`" + item + "`"
-	}
-	return fmt.Sprintf("%v %v", strings.Join(items, "."), s.Sort)
-}
+type PageSortFields = page.SortFields
 
-type PageOption[T any] struct {
-	Db       *gorm.DB     // 必须
-	Query    PageQuery    // 必须
-	DefOrder *PageOrder   // 默认排序
-	Orders   *[]PageOrder // 服务端指定多个排序条件
-	Handle   func(T) any
-}
+type PageOption[T any] page.PageOption[T]
 
 // 创建一个新的分页查询
 func NewPage[T any](option PageOption[T]) (result PageResult) {
-
-	if option.Query.Order == nil && option.Orders == nil {
-		option.Query.Order = option.DefOrder
-	}
-
-	var count int64
-	var items = make([]T, 0)
-	option.Db.Count(&count)
-	result.Total = count
-	result.Page = option.Query.Limit.GetPage()
-	result.Size = option.Query.Limit.GetSize()
-	if count > 0 {
-		option.Db = option.Db.Order(option.BuildOrderString())
-		option.Db.Limit(option.Query.Limit.GetSize()).Offset(int(option.Query.Limit.Offset())).Scan(&items)
-
-		if option.Handle != nil {
-			result.Items = vingo.ForEach(items, func(item T, index int) any {
-				return option.Handle(item)
-			})
-			return
-		}
-	}
-	result.Items = items
-	return
+	return page.NewPage[T](page.PageOption[T](option))
 }
 
 func (s *PageOption[T]) BuildOrderString() string {
-	// 默认排序
-	if s.Query.Order == nil && s.Orders == nil {
-		return "`id` desc"
-	}
+	return (*page.PageOption[T])(s).BuildOrderString()
+}
 
-	if s.Query.Order != nil {
-		s.Orders = &[]PageOrder{*s.Query.Order}
-	}
+type CursorPageOption[T any] page.CursorPageOption[T]
 
-	var orders = make([]string, 0)
-	for _, item := range *s.Orders {
-		orders = append(orders, item.HandleColumn())
-	}
-	return strings.Join(orders, ",")
+// 创建一个新的游标分页查询，适用于大表或需要稳定翻页的场景
+func NewCursorPage[T any](option CursorPageOption[T]) page.CursorResult {
+	return page.NewCursorPage[T](page.CursorPageOption[T](option))
 }
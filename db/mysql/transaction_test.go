@@ -0,0 +1,106 @@
+package mysql
+
+import (
+	"path/filepath"
+	"testing"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/lgdzz/vingo-utils-v2/db/transaction"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type txRow struct {
+	Id   uint
+	Name string
+}
+
+func sqliteApi(t *testing.T) *DbApi {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&txRow{}); err != nil {
+		t.Fatal(err)
+	}
+	return &DbApi{DB: db}
+}
+
+func countRows(s *DbApi) (count int64) {
+	s.DB.Model(&txRow{}).Count(&count)
+	return
+}
+
+// 通过WithContext、CommitContext加入外层事务，内层失败只回滚内层
+func TestCommitNested(t *testing.T) {
+	var s = sqliteApi(t)
+	var calls []string
+	s.Commit(func(tx *gorm.DB) {
+		tx.Create(&txRow{Name: "a"})
+		s.WithContext(tx.Statement.Context).CreateInBatches(&[]txRow{{Name: "b"}, {Name: "c"}})
+		func() {
+			defer func() { _ = recover() }()
+			s.CommitContext(tx.Statement.Context, func(tx *gorm.DB) {
+				tx.Create(&txRow{Name: "d"})
+				s.AfterCommit(tx, func() { calls = append(calls, "rollback") })
+				panic("内层失败")
+			})
+		}()
+		s.AfterCommit(tx, func() { calls = append(calls, "outer") })
+		if len(calls) != 0 {
+			t.Fatalf("提交前执行了回调：%v", calls)
+		}
+	})
+	if count := countRows(s); count != 3 {
+		t.Fatalf("记录数错误：%v", count)
+	}
+	if len(calls) != 1 || calls[0] != "outer" {
+		t.Fatalf("提交后回调错误：%v", calls)
+	}
+}
+
+// 没有绑定外层事务时Commit开启独立事务
+func TestCommitIndependent(t *testing.T) {
+	var s = sqliteApi(t)
+	func() {
+		defer func() { _ = recover() }()
+		s.Commit(func(tx *gorm.DB) {
+			s.Commit(func(tx *gorm.DB) {
+				tx.Create(&txRow{Name: "a"})
+			})
+			panic("外层失败")
+		})
+	}()
+	if count := countRows(s); count != 1 {
+		t.Fatalf("独立事务被回滚：%v", count)
+	}
+}
+
+// 仅死锁错误重试
+func TestCommitRetry(t *testing.T) {
+	transaction.RetryDelay = 0
+	var s = sqliteApi(t)
+	var attempts int
+	s.Commit(func(tx *gorm.DB) {
+		attempts++
+		if attempts == 1 {
+			panic(&mysqlDriver.MySQLError{Number: 1213, Message: "Deadlock found"})
+		}
+	}, transaction.Option{Retry: 1})
+	if attempts != 2 {
+		t.Fatalf("死锁未重试：%v", attempts)
+	}
+
+	attempts = 0
+	func() {
+		defer func() { _ = recover() }()
+		s.Commit(func(tx *gorm.DB) {
+			attempts++
+			panic(&mysqlDriver.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		}, transaction.Option{Retry: 1})
+	}()
+	if attempts != 1 {
+		t.Fatalf("非死锁错误被重试：%v", attempts)
+	}
+}
@@ -0,0 +1,285 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：游标分页（keyset），按排序字段值定位，避免大表LIMIT/OFFSET和COUNT的性能问题
+// *****************************************************************************
+
+package page
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+var cursorSchemaCache = &sync.Map{}
+
+// 游标分页参数
+type Cursor struct {
+	Cursor    string `form:"cursor"`    // 游标，为空时从第一页开始
+	Direction string `form:"direction"` // next-下一页|prev-上一页，默认next
+	Size      int    `form:"size"`
+}
+
+// 获取每页条数，未传时使用DefaultSize，设置了MaxSize且超过时取MaxSize
+func (s *Cursor) GetSize() int {
+	var limit = Limit{Size: s.Size}
+	return limit.GetSize()
+}
+
+func (s *Cursor) IsPrev() bool {
+	return strings.ToLower(s.Direction) == CursorPrev
+}
+
+type CursorResult struct {
+	Size    int    `json:"size"`
+	Total   *int64 `json:"total,omitempty"` // 开启统计时返回
+	Next    string `json:"next"`            // 下一页游标，为空表示没有下一页
+	Prev    string `json:"prev"`            // 上一页游标，为空表示没有上一页
+	HasMore bool   `json:"hasMore"`         // 当前方向上是否还有数据
+	Items   any    `json:"items"`           // 查询数据列表
+}
+
+type CursorOption struct {
+	Cursor      Cursor
+	Order       []Order    // 排序字段，字段值不能为NULL，且最后一个字段必须唯一（通常为id），默认id desc
+	SortFields  SortFields // 允许排序的字段，为空时不限制
+	MaxSize     int        // 每页最大条数，默认MaxSize（0不限制）
+	WithCount   bool       // 是否统计总数，默认不统计
+	ApproxCount bool       // 使用表统计信息估算总数（仅pgsql，查询有过滤条件时仍使用COUNT），需开启WithCount
+}
+
+// 游标内容
+type cursorPayload struct {
+	Order  string `json:"o"`
+	Values []any  `json:"v"`
+}
+
+// 排序签名，防止不同排序规则的游标混用
+func cursorOrderSign(orders []Order) string {
+	var items = make([]string, 0, len(orders))
+	for _, item := range orders {
+		items = append(items, item.Column+" "+strings.ToLower(item.Sort))
+	}
+	return strings.Join(items, ",")
+}
+
+func encodeCursor(orders []Order, values []any) string {
+	text, err := json.Marshal(cursorPayload{Order: cursorOrderSign(orders), Values: values})
+	if err != nil {
+		panic(err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(text)
+}
+
+func decodeCursor(orders []Order, cursor string) []any {
+	text, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		panic("分页游标无效")
+	}
+	var payload cursorPayload
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	if err = decoder.Decode(&payload); err != nil || payload.Order != cursorOrderSign(orders) || len(payload.Values) != len(orders) {
+		panic("分页游标无效")
+	}
+	return payload.Values
+}
+
+// 构建游标定位条件：(c1 > v1) OR (c1 = v1 AND c2 > v2) ...
+func buildKeysetWhere(db *gorm.DB, orders []Order, values []any, prev bool) (string, []any) {
+	var groups = make([]string, 0, len(orders))
+	var args = make([]any, 0)
+	for i, item := range orders {
+		var conditions = make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, fmt.Sprintf("%v = ?", db.Statement.Quote(orders[j].Column)))
+			args = append(args, values[j])
+		}
+		var op = ">"
+		if (strings.ToLower(item.Sort) == "desc") != prev {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("%v %v ?", db.Statement.Quote(item.Column), op))
+		args = append(args, values[i])
+		groups = append(groups, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return strings.Join(groups, " OR "), args
+}
+
+// 构建排序，向前翻页时反转排序方向
+func buildKeysetOrder(db *gorm.DB, orders []Order, prev bool) string {
+	var items = make([]string, 0, len(orders))
+	for _, item := range orders {
+		var sort = strings.ToLower(item.Sort)
+		if prev {
+			sort = vingo.SY(sort == "desc", "asc", "desc")
+		}
+		items = append(items, db.Statement.Quote(item.Column)+" "+sort)
+	}
+	return strings.Join(items, ",")
+}
+
+// 读取记录中的排序字段值
+func cursorValues[T any](db *gorm.DB, row T, orders []Order) []any {
+	var values = make([]any, 0, len(orders))
+	if m, ok := any(row).(map[string]any); ok {
+		for _, item := range orders {
+			values = append(values, m[columnName(item.Column)])
+		}
+		return values
+	}
+
+	s, err := schema.Parse(&row, cursorSchemaCache, db.NamingStrategy)
+	if err != nil {
+		panic(err.Error())
+	}
+	var rowValue = reflect.ValueOf(&row).Elem()
+	for _, item := range orders {
+		field := s.LookUpField(columnName(item.Column))
+		if field == nil {
+			panic(fmt.Sprintf("游标分页排序字段[%v]不在查询结果中", item.Column))
+		}
+		value, _ := field.ValueOf(context.Background(), rowValue)
+		values = append(values, value)
+	}
+	return values
+}
+
+// 去除表名前缀
+func columnName(column string) string {
+	var items = strings.Split(column, ".")
+	return items[len(items)-1]
+}
+
+// 估算表记录数（pgsql表统计信息），table为空时使用查询的主表
+func ApproxCount(db *gorm.DB, table string) int64 {
+	if table == "" {
+		if db.Statement.Table == "" && db.Statement.Model != nil {
+			_ = db.Statement.Parse(db.Statement.Model)
+		}
+		table = db.Statement.Table
+	}
+	var count int64
+	db.Session(&gorm.Session{NewDB: true}).Raw("SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = to_regclass(?)", table).Scan(&count)
+	return count
+}
+
+// 估算的是整表记录数，仅pgsql且查询没有过滤、关联和分组条件时可以使用，否则应使用COUNT
+func approxCountable(db *gorm.DB) bool {
+	if db.Dialector.Name() != "postgres" || len(db.Statement.Joins) > 0 {
+		return false
+	}
+	for _, name := range []string{"WHERE", "GROUP BY"} {
+		if _, ok := db.Statement.Clauses[name]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// 创建一个新的游标分页查询
+func NewCursor[T any](db *gorm.DB, option CursorOption, handle func(T) any) (result CursorResult) {
+	var orders = append([]Order{}, option.Order...)
+	if len(orders) == 0 {
+		orders = []Order{{Column: "id", Sort: "desc"}}
+	}
+	for index := range orders {
+		if option.SortFields != nil {
+			orders[index] = option.SortFields.MustResolve(orders[index])
+		}
+		orders[index].checkSort()
+	}
+	var limit = Limit{Size: option.Cursor.Size}
+	var size = limit.GetSizeLimit(0, option.MaxSize)
+	// 没有游标时总是从第一页开始
+	var prev = option.Cursor.IsPrev() && option.Cursor.Cursor != ""
+	result.Size = size
+
+	if option.WithCount {
+		var count int64
+		if option.ApproxCount && approxCountable(db) {
+			count = ApproxCount(db, "")
+		} else {
+			db.Session(&gorm.Session{}).Count(&count)
+		}
+		result.Total = &count
+	}
+
+	var values []any
+	if option.Cursor.Cursor != "" {
+		values = decodeCursor(orders, option.Cursor.Cursor)
+		where, args := buildKeysetWhere(db, orders, values, prev)
+		db = db.Where(where, args...)
+	}
+
+	var items = make([]T, 0)
+	db.Order(buildKeysetOrder(db, orders, prev)).Limit(size + 1).Scan(&items)
+
+	result.HasMore = len(items) > size
+	if result.HasMore {
+		items = items[:size]
+	}
+	if prev {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if len(items) > 0 {
+		first := encodeCursor(orders, cursorValues(db, items[0], orders))
+		last := encodeCursor(orders, cursorValues(db, items[len(items)-1], orders))
+		if prev {
+			result.Next = last
+			result.Prev = vingo.SY(result.HasMore, first, "")
+		} else {
+			result.Next = vingo.SY(result.HasMore, last, "")
+			result.Prev = vingo.SY(values != nil, first, "")
+		}
+	}
+
+	if handle != nil {
+		result.Items = vingo.ForEach(items, func(item T, index int) any {
+			return handle(item)
+		})
+		return
+	}
+	result.Items = items
+	return
+}
+
+type CursorPageOption[T any] struct {
+	Db          *gorm.DB    // 必须
+	Cursor      Cursor      // 必须
+	Orders      []Order     // 排序字段，字段值不能为NULL，且最后一个字段必须唯一，默认id desc
+	SortFields  SortFields  // 允许排序的字段，为空时不限制
+	MaxSize     int         // 每页最大条数，默认MaxSize（0不限制）
+	WithCount   bool        // 是否统计总数
+	ApproxCount bool        // 使用表统计信息估算总数（仅pgsql，查询有过滤条件时仍使用COUNT），需开启WithCount
+	Handle      func(T) any // 处理函数
+}
+
+// 创建一个新的游标分页查询，适用于大表或需要稳定翻页的场景
+func NewCursorPage[T any](option CursorPageOption[T]) CursorResult {
+	return NewCursor[T](option.Db, CursorOption{
+		Cursor:      option.Cursor,
+		Order:       option.Orders,
+		SortFields:  option.SortFields,
+		MaxSize:     option.MaxSize,
+		WithCount:   option.WithCount,
+		ApproxCount: option.ApproxCount,
+	}, option.Handle)
+}
@@ -1,7 +1,10 @@
 package page
 
 import (
+	"context"
 	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/db/filter"
+	"github.com/lgdzz/vingo-utils-v2/pool"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
 	"gorm.io/gorm"
 	"strings"
@@ -11,14 +14,25 @@ import (
 // page.New[flow.Approval](pool, page.Option{
 // 		Limit: page.Limit{Page: query.Page, Size: query.Size},
 // })
+//
+// mysql、pgsql包中的PageResult、PageLimit、PageOrder、PageQuery均为本包类型的别名，
+// NewPage按db的方言引用字段名，各驱动共用同一套分页逻辑
 
 type Result struct {
-	Page  int   `json:"page"`
-	Size  int   `json:"size"`
-	Total int64 `json:"total"` // 总的记录数
-	Items any   `json:"items"` // 查询数据列表
+	Page    int   `json:"page"`
+	Size    int   `json:"size"`
+	Total   int64 `json:"total"`             // 总的记录数，跳过统计时为-1
+	HasMore bool  `json:"hasMore,omitempty"` // 是否还有下一页，跳过统计时返回
+	Items   any   `json:"items"`             // 查询数据列表
 }
 
+// 每页默认条数和最大条数，可在PageOption中按查询覆盖
+// MaxSize默认为0不限制，需要限制前端传入的条数时设置，如page.MaxSize = 1000
+var (
+	DefaultSize = 10
+	MaxSize     = 0
+)
+
 type Limit struct {
 	Page int `form:"page"`
 	Size int `form:"size"`
@@ -32,32 +46,69 @@ func (s *Limit) GetPage() int {
 	}
 }
 
+// 获取每页条数，未传时使用DefaultSize，设置了MaxSize且超过时取MaxSize
 func (s *Limit) GetSize() int {
-	if s.Size > 0 {
-		return s.Size
-	} else {
-		return 10
+	return s.GetSizeLimit(0, 0)
+}
+
+// 获取每页条数，defaultSize、maxSize小于等于0时使用全局配置，最大条数仍为0时不限制
+func (s *Limit) GetSizeLimit(defaultSize int, maxSize int) int {
+	if defaultSize <= 0 {
+		defaultSize = DefaultSize
 	}
+	if maxSize <= 0 {
+		maxSize = MaxSize
+	}
+	var size = s.Size
+	if size <= 0 {
+		size = defaultSize
+	}
+	if maxSize > 0 {
+		size = min(size, maxSize)
+	}
+	return size
 }
 
 func (s *Limit) Offset() int64 {
-	if s.Page > 0 {
-		return int64((s.Page - 1) * s.Size)
-	} else {
-		return 0
-	}
+	return s.offset(s.GetSize())
+}
+
+func (s *Limit) offset(size int) int64 {
+	return int64((s.GetPage() - 1) * size)
 }
 
 type Order struct {
 	Column string `form:"sortField"`
 	Sort   string `form:"sortOrder"`
+	Nulls  string `form:"sortNulls"` // first|last，空值排在最前或最后，默认由数据库决定
 }
 
-func (s *Order) HandleColumn() string {
+// 校验排序字段名、排序方向和空值位置
+func (s *Order) Check() error {
+	if filter.Column(s.Column).Check() != nil {
+		return &vingo.ParamException{Field: "sortField", Message: fmt.Sprintf("排序字段[%v]不合法", s.Column)}
+	}
 	var sort = strings.ToLower(s.Sort)
 	if sort != "asc" && sort != "desc" {
-		panic("存在sql注入的风险")
+		return &vingo.ParamException{Field: "sortOrder", Message: fmt.Sprintf("排序方向[%v]不合法，只能为asc或desc", s.Sort)}
+	}
+	var nulls = strings.ToLower(s.Nulls)
+	if nulls != "" && nulls != "first" && nulls != "last" {
+		return &vingo.ParamException{Field: "sortNulls", Message: fmt.Sprintf("空值排序[%v]不合法，只能为first或last", s.Nulls)}
 	}
+	return nil
+}
+
+func (s *Order) checkSort() {
+	if err := s.Check(); err != nil {
+		panic(err)
+	}
+}
+
+// 生成排序语句，字段名使用MySQL反引号引用
+// Deprecated: 请使用Build，按数据库方言引用字段名
+func (s *Order) HandleColumn() string {
+	s.checkSort()
 	var items = strings.Split(s.Column, ".")
 	for index, item := range items {
 		items[index] = "`" + item + "`"
@@ -65,34 +116,199 @@ func (s *Order) HandleColumn() string {
 	return fmt.Sprintf("%v %v", strings.Join(items, "."), s.Sort)
 }
 
+// 生成排序语句，按db的方言引用字段名
+// pgsql、sqlite使用NULLS FIRST/LAST，其他数据库使用IS NULL排序模拟
+func (s *Order) Build(db *gorm.DB) string {
+	s.checkSort()
+	var column = quote(db, s.Column)
+	var nulls = strings.ToLower(s.Nulls)
+	if nulls == "" {
+		return fmt.Sprintf("%v %v", column, s.Sort)
+	}
+	if db != nil && (db.Dialector.Name() == "postgres" || db.Dialector.Name() == "sqlite") {
+		return fmt.Sprintf("%v %v NULLS %v", column, s.Sort, strings.ToUpper(nulls))
+	}
+	return fmt.Sprintf("%v IS NULL %v,%v %v", column, vingo.SY(nulls == "first", "desc", "asc"), column, s.Sort)
+}
+
+// 允许排序的字段，key为前端传入的字段名，value为数据库字段名，value为空时与key相同
+// sortFields := page.SortFields{"createdAt": "created_at", "id": ""}
+type SortFields map[string]string
+
+// 将前端传入的排序字段转换为数据库字段，不在白名单中时返回错误
+func (s SortFields) Resolve(order Order) (Order, error) {
+	column, ok := s[order.Column]
+	if !ok {
+		return order, &vingo.ParamException{Field: "sortField", Message: fmt.Sprintf("不支持按[%v]排序", order.Column)}
+	}
+	if column != "" {
+		order.Column = column
+	}
+	return order, order.Check()
+}
+
+// 转换排序字段，不在白名单中时panic
+func (s SortFields) MustResolve(order Order) Order {
+	order, err := s.Resolve(order)
+	if err != nil {
+		panic(err)
+	}
+	return order
+}
+
+// 按方言引用字段名，db为空时使用MySQL反引号
+func quote(db *gorm.DB, column string) string {
+	if db == nil || db.Statement == nil {
+		var items = strings.Split(column, ".")
+		for index, item := range items {
+			items[index] = "`" + item + "`"
+		}
+		return strings.Join(items, ".")
+	}
+	return db.Statement.Quote(column)
+}
+
 type Option struct {
-	Limit Limit
-	Order []Order
+	Limit       Limit
+	Order       []Order
+	SortFields  SortFields // 允许排序的字段，为空时不限制
+	DefaultSize int        // 每页默认条数，默认DefaultSize
+	MaxSize     int        // 每页最大条数，默认MaxSize（0不限制）
+	SkipCount   bool       // 跳过COUNT查询，通过多查询一条判断是否还有下一页
 }
 
 // 创建一个新的分页查询
 func New[T any](db *gorm.DB, option Option, handle func(T) any) (result Result) {
-	var count int64
+	var orders = make([]Order, 0, len(option.Order))
+	for _, item := range option.Order {
+		if option.SortFields != nil {
+			item = option.SortFields.MustResolve(item)
+		}
+		orders = append(orders, item)
+	}
+	return NewPage[T](PageOption[T]{
+		Db:          db,
+		Query:       Query{Limit: option.Limit},
+		Orders:      &orders,
+		Handle:      handle,
+		DefaultSize: option.DefaultSize,
+		MaxSize:     option.MaxSize,
+		SkipCount:   option.SkipCount,
+	})
+}
+
+// 分页请求参数
+type Query struct {
+	Limit Limit
+	Order *Order
+}
+
+type PageOption[T any] struct {
+	Db          *gorm.DB       // 必须
+	Query       Query          // 必须
+	DefOrder    *Order         // 默认排序
+	Orders      *[]Order       // 服务端指定多个排序条件
+	SortFields  SortFields     // 允许前端排序的字段（Query.Order），为空时不限制
+	DefaultSize int            // 每页默认条数，默认DefaultSize
+	MaxSize     int            // 每页最大条数，默认MaxSize（0不限制）
+	Handle      func(T) any    // 处理函数
+	HandlePool  func(*T)       // 处理函数（协程池）
+	PoolResult  *[]pool.Result // 协程池结果，传入非空指针时写入
+	MaxWorkers  int            // 最大协程数
+	SkipCount   bool           // 跳过COUNT查询，通过多查询一条判断是否还有下一页
+	ApproxCount bool           // 使用表统计信息估算总数（仅pgsql，查询有过滤条件时仍使用COUNT），估算值仅用于Total
+}
+
+// 创建一个新的分页查询
+func NewPage[T any](option PageOption[T]) (result Result) {
+
+	if option.Query.Order != nil && option.SortFields != nil {
+		var order = option.SortFields.MustResolve(*option.Query.Order)
+		option.Query.Order = &order
+	}
+	if option.Query.Order == nil && option.Orders == nil {
+		option.Query.Order = option.DefOrder
+	}
+
+	var count int64 = -1
 	var items = make([]T, 0)
-	db.Count(&count)
+	// 估算值可能为0（表未analyze），不能据此跳过数据查询
+	var approx = option.ApproxCount && approxCountable(option.Db)
+	if approx {
+		count = ApproxCount(option.Db, "")
+	} else if !option.SkipCount {
+		option.Db.Count(&count)
+	}
 	result.Total = count
-	result.Page = option.Limit.GetPage()
-	result.Size = option.Limit.GetSize()
-	if count > 0 {
-		db = db.Order(BuildOrderString(option.Order))
-		db.Limit(option.Limit.GetSize()).Offset(int(option.Limit.Offset())).Scan(&items)
+	result.Page = option.Query.Limit.GetPage()
+	result.Size = option.Query.Limit.GetSizeLimit(option.DefaultSize, option.MaxSize)
+	if count != 0 || approx {
+		var offset = option.Query.Limit.offset(result.Size)
+		option.Db = option.Db.Order(option.BuildOrderString())
+		option.Db.Limit(limitSize(result.Size, option.SkipCount)).Offset(int(offset)).Scan(&items)
+		items, result.HasMore = trimMore(items, result.Size, option.SkipCount)
+		if approx {
+			// 估算值偏小时至少为已查到的条数
+			result.Total = max(result.Total, offset+int64(len(items)))
+		}
 
-		if handle != nil {
+		if option.Handle != nil {
 			result.Items = vingo.ForEach(items, func(item T, index int) any {
-				return handle(item)
+				return option.Handle(item)
 			})
 			return
+		} else if option.HandlePool != nil {
+			if option.MaxWorkers <= 0 {
+				option.MaxWorkers = 100
+			}
+			results, _ := pool.Map(context.Background(), items, option.MaxWorkers, func(_ context.Context, _ T, index int) (pool.Result, error) {
+				return pool.BusinessHandle(&items[index], func(object *T) any {
+					option.HandlePool(object)
+					return nil
+				}), nil
+			})
+			if option.PoolResult != nil {
+				*option.PoolResult = results
+			}
 		}
 	}
 	result.Items = items
 	return
 }
 
+func (s *PageOption[T]) BuildOrderString() string {
+	// 默认排序
+	if s.Query.Order == nil && (s.Orders == nil || len(*s.Orders) == 0) {
+		return quote(s.Db, "id") + " desc"
+	}
+
+	if s.Query.Order != nil {
+		s.Orders = &[]Order{*s.Query.Order}
+	}
+
+	var orders = make([]string, 0)
+	for _, item := range *s.Orders {
+		orders = append(orders, item.Build(s.Db))
+	}
+	return strings.Join(orders, ",")
+}
+
+// 跳过统计时多查询一条用于判断是否还有下一页
+func limitSize(size int, skipCount bool) int {
+	if skipCount {
+		return size + 1
+	}
+	return size
+}
+
+// 去掉多查询的一条，返回是否还有下一页
+func trimMore[T any](items []T, size int, skipCount bool) ([]T, bool) {
+	if skipCount && len(items) > size {
+		return items[:size], true
+	}
+	return items, false
+}
+
 func OrderDefault(order *Order) []Order {
 	if order != nil {
 		return []Order{*order}
@@ -116,6 +332,8 @@ func (s *Order) Default() []Order {
 	return OrderDefault(s)
 }
 
+// 生成排序语句，字段名使用MySQL反引号引用
+// Deprecated: 请使用NewPage，按数据库方言引用字段名
 func BuildOrderString(order []Order) string {
 	if len(order) == 0 {
 		return "`id` desc"
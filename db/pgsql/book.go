@@ -3,242 +3,166 @@ package pgsql
 import (
 	"database/sql"
 	"fmt"
-	"github.com/lgdzz/vingo-utils-v2/vingo"
-	"os"
-	"path/filepath"
-	"text/template"
+	"github.com/lgdzz/vingo-utils-v2/db/book"
+	"github.com/lgdzz/vingo-utils-v2/db/schema"
 	"time"
 )
 
-type TableItem struct {
-	Name    string
-	Comment string
-	Columns []Column
-}
-
-type Database struct {
-	Name        string
-	ReleaseTime string
-	Tables      []TableItem
-}
-
-const booktpl = `
-<!DOCTYPE html>
-<html>
-<head>
-  <title>{{ .Name }} 数据字典</title>
-  <style>
-    body {
-      margin: 0 50px;
-      font-size: 14px;
-      padding-bottom: 50px;
-    }
-
-    table {
-      border-collapse: collapse;
-      width: 100%;
-    }
-
-    th,
-    td {
-      border: 1px solid #ddd;
-      padding: 8px;
-      text-align: left;
-    }
-
-    th {
-      background-color: #f2f2f2;
-    }
+type TableItem = book.Table
 
-    .main {
-      display: flex;
-      height: 85vh;
-    }
+type Database = book.Database
 
-    .menu {
-      margin-right: 50px;
-      height: 100%;
-      overflow: auto;
-    }
-
-    .menu a {
-      display: flex;
-      color: #2196f3;
-      font-size: 12px;
-      text-decoration: inherit;
-    }
-
-    .menu a div:nth-child(1) {
-      flex: 1;
-    }
-
-    .menu a div:nth-child(2) {
-      color: #ccc;
-      margin: 0 10px;
-    }
-
-    .table {
-      flex: 1;
-      height: 100%;
-      overflow: auto;
-    }
-  </style>
-</head>
-<body>
-  <h1>{{ .Name }} 数据字典<span style="float:right">{{ .ReleaseTime }}</span></h1>
-  <div class="main">
-	  <div class="menu">
-	  {{ range .Tables }}
-	  <a href="#{{ .Name }}">
-      	<div>{{ .Name }}</div>
-        <div>{{ .Comment }}</div>
-      </a>
-	  {{ end }}
-	  </div>
-
-  	  <div class="table">
-	  {{ range .Tables }}
-	  <h2 id="{{ .Name }}">{{ .Name }} {{ .Comment }}</h2>
-	
-	  <table>
-		<tr>
-		  <th>字段名</th>
-		  <th>数据类型</th>
-		  <th>允许空值</th>
-		  <th>键</th>
-		  <th>默认值</th>
-		  <th>备注</th>
-		</tr>
-		{{ range .Columns }}
-		<tr>
-		  <td>{{ .Field }}</td>
-		  <td>{{ .Type }}</td>
-		  <td>{{ .Null }}</td>
-		  <td>{{ .Key }}</td>
-		  <td>{{ .Default }}</td>
-		  <td>{{ .Comment }}</td>
-		</tr>
-		{{ end }}
-	  </table>
-	
-	  {{ end }}
-	  </div>
-  </div>
-</body>
-</html>
-`
+// 生成数据库字典，formats可选：html、md、json、xlsx，默认html
+func (s *DbApi) BuildBook(formats ...string) error {
+	database, err := s.Schema()
+	if err != nil {
+		return err
+	}
+	files, err := book.Write(database, "dbbook", formats...)
+	for _, file := range files {
+		fmt.Println("数据字典：", file)
+	}
+	return err
+}
 
-func (s *DbApi) BuildBook() error {
-	var tables []TableItem
-	var dbName string
+// 读取数据库结构，包含字段、索引和外键，tables为空时读取全部表
+func (s *DbApi) Schema(tables ...string) (Database, error) {
+	var database = Database{Dialect: schema.DialectPostgres, ReleaseTime: time.Now().Format("2006年01月02日")}
 
 	// PostgreSQL 获取当前数据库名
-	err := s.DB.Raw("SELECT current_database()").Row().Scan(&dbName)
+	err := s.DB.Raw("SELECT current_database()").Row().Scan(&database.Name)
 	if err != nil {
-		return err
+		return database, err
 	}
 
-	vingo.Mkdir("dbbook")
-	var outputFilePath = filepath.Join("dbbook", fmt.Sprintf("%v_%v.html", dbName, time.Now().Format("20060102")))
-
 	// PostgreSQL 获取所有表名及注释
-	rows, err := s.DB.Raw(`
-		SELECT c.relname AS table_name, obj_description(c.oid) AS comment
-		FROM pg_class c
-		JOIN pg_namespace n ON n.oid = c.relnamespace
-		WHERE c.relkind = 'r' AND n.nspname = 'public'
-	`).Rows()
+	var query = s.DB.Table("pg_class c").
+		Select("c.relname AS table_name, obj_description(c.oid) AS comment").
+		Joins("JOIN pg_namespace n ON n.oid = c.relnamespace").
+		Where("c.relkind = 'r' AND n.nspname = 'public'")
+	if len(tables) > 0 {
+		query = query.Where("c.relname IN ?", tables)
+	}
+	rows, err := query.Order("c.relname").Rows()
 	if err != nil {
-		return err
+		return database, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var tableName string
+		var table TableItem
 		var comment sql.NullString
 
-		if err := rows.Scan(&tableName, &comment); err != nil {
-			return err
+		if err := rows.Scan(&table.Name, &comment); err != nil {
+			return database, err
 		}
-
-		tableComment := ""
 		if comment.Valid {
-			tableComment = comment.String
+			table.Comment = comment.String
 		}
+		database.Tables = append(database.Tables, table)
+	}
+
+	for i := range database.Tables {
+		var table = &database.Tables[i]
 
-		// 获取列信息
-		columns, err := s.getTableColumns(tableName)
+		// 获取列信息，已按字段顺序排序
+		columns, err := s.getTableColumns(table.Name)
 		if err != nil {
-			return err
+			return database, err
 		}
-
-		// 字段排序
-		fieldOrder := s.getFieldOrder(tableName)
-		sortedColumns := make([]Column, len(columns))
-		for i, field := range fieldOrder {
-			for _, col := range columns {
-				if col.Field == field {
-					sortedColumns[i] = col
-					break
-				}
+		table.Columns = make([]schema.Column, 0, len(columns))
+		for _, col := range columns {
+			var item = schema.Column{Field: col.Field, Type: col.Type, Null: col.Null, Key: col.Key, Extra: col.Extra, Comment: col.Comment}
+			if col.Default.Valid && col.Default.String != "" {
+				item.Default = &col.Default.String
 			}
+			table.Columns = append(table.Columns, item)
 		}
 
-		tables = append(tables, TableItem{
-			Name:    tableName,
-			Comment: tableComment,
-			Columns: sortedColumns,
-		})
-	}
-
-	database := Database{
-		Name:        dbName,
-		Tables:      tables,
-		ReleaseTime: time.Now().Format("2006年01月02日"),
-	}
-
-	t, err := template.New("tpl").Parse(booktpl)
-	if err != nil {
-		return err
+		if table.Indexes, err = s.getTableIndexes(table.Name); err != nil {
+			return database, err
+		}
+		if table.ForeignKeys, err = s.getTableForeignKeys(table.Name); err != nil {
+			return database, err
+		}
 	}
+	return database, nil
+}
 
-	outputFile, err := os.Create(outputFilePath)
+// 获取表索引（PostgreSQL）
+func (s *DbApi) getTableIndexes(tableName string) ([]schema.Index, error) {
+	var indexes []schema.Index
+	rows, err := s.DB.Raw(`
+		SELECT i.relname AS index_name, ix.indisunique, ix.indisprimary, a.attname AS column_name
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE t.relname = ? AND n.nspname = 'public'
+		ORDER BY ix.indisprimary DESC, i.relname, k.ord
+	`, tableName).Rows()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer outputFile.Close()
+	defer rows.Close()
 
-	if err := t.Execute(outputFile, database); err != nil {
-		return err
+	for rows.Next() {
+		var name, column string
+		var unique, primary bool
+		if err := rows.Scan(&name, &unique, &primary, &column); err != nil {
+			return nil, err
+		}
+		indexes = schema.AppendIndex(indexes, name, column, unique, primary)
 	}
-
-	return nil
+	return indexes, nil
 }
 
-// 获取字段顺序
-func (s *DbApi) getFieldOrder(tableName string) []string {
-	var fields []string
-
+// 获取表外键（PostgreSQL）
+func (s *DbApi) getTableForeignKeys(tableName string) ([]schema.ForeignKey, error) {
+	var foreignKeys []schema.ForeignKey
 	rows, err := s.DB.Raw(`
-		SELECT column_name
-		FROM information_schema.columns
-		WHERE table_schema = 'public' AND table_name = ?
-		ORDER BY ordinal_position
+		SELECT con.conname, a.attname, rt.relname, ra.attname, con.confupdtype, con.confdeltype
+		FROM pg_constraint con
+		JOIN pg_class t ON t.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class rt ON rt.oid = con.confrelid
+		JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = rt.oid AND ra.attnum = k.refnum
+		WHERE con.contype = 'f' AND t.relname = ? AND n.nspname = 'public'
+		ORDER BY con.conname, k.ord
 	`, tableName).Rows()
 	if err != nil {
-		return fields
+		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var field string
-		if err := rows.Scan(&field); err != nil {
-			continue
+		var name, column, refTable, refColumn, onUpdate, onDelete string
+		if err := rows.Scan(&name, &column, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			return nil, err
 		}
-		fields = append(fields, field)
+		foreignKeys = schema.AppendForeignKey(foreignKeys, name, column, refTable, refColumn, foreignKeyAction(onUpdate), foreignKeyAction(onDelete))
 	}
+	return foreignKeys, nil
+}
 
-	return fields
+// 外键动作代码转换为sql关键字
+func foreignKeyAction(code string) string {
+	switch code {
+	case "r":
+		return "RESTRICT"
+	case "c":
+		return "CASCADE"
+	case "n":
+		return "SET NULL"
+	case "d":
+		return "SET DEFAULT"
+	default:
+		return "NO ACTION"
+	}
 }
 
 // 获取表字段信息（PostgreSQL）
@@ -250,7 +174,9 @@ func (s *DbApi) getTableColumns(tableName string) ([]Column, error) {
 			a.attname AS column_name,
 			format_type(a.atttypid, a.atttypmod) AS data_type,
 			NOT a.attnotnull AS is_nullable,
-			CASE WHEN ct.contype = 'p' THEN 'PRI' ELSE '' END AS column_key,
+			CASE WHEN EXISTS (
+				SELECT 1 FROM pg_constraint ct WHERE ct.conrelid = c.oid AND ct.contype = 'p' AND a.attnum = ANY(ct.conkey)
+			) THEN 'PRI' ELSE '' END AS column_key,
 			COALESCE(pg_get_expr(d.adbin, d.adrelid), '') AS column_default,
 			col_description(a.attrelid, a.attnum) AS column_comment
 		FROM
@@ -258,7 +184,6 @@ func (s *DbApi) getTableColumns(tableName string) ([]Column, error) {
 		JOIN pg_class c ON a.attrelid = c.oid
 		JOIN pg_namespace n ON c.relnamespace = n.oid
 		LEFT JOIN pg_attrdef d ON d.adrelid = c.oid AND d.adnum = a.attnum
-		LEFT JOIN pg_constraint ct ON ct.conrelid = c.oid AND a.attnum = ANY(ct.conkey)
 		WHERE
 			a.attnum > 0
 			AND NOT a.attisdropped
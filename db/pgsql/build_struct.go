@@ -6,7 +6,9 @@ import (
 	"github.com/duke-git/lancet/v2/strutil"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -63,7 +65,18 @@ type Column struct {
 	JsonName string
 }
 
+// 生成模型选项，pgsql暂不支持重新生成模式及service、router文件
+type ModelOption struct {
+	Include []string // 未指定表名时按模式匹配表名，如：sys_*，为空时匹配全部
+	Exclude []string // 排除的表名模式
+}
+
 func (s *DbApi) CreateDbModel(tableNames ...string) (bool, error) {
+	return s.CreateDbModelWithOption(ModelOption{}, tableNames...)
+}
+
+// 生成数据库模型，已存在的文件不会覆盖，未指定表名时生成public下按Include/Exclude匹配的全部表
+func (s *DbApi) CreateDbModelWithOption(option ModelOption, tableNames ...string) (bool, error) {
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Println("请检查数据库是否正常连接")
@@ -71,6 +84,9 @@ func (s *DbApi) CreateDbModel(tableNames ...string) (bool, error) {
 		}
 	}()
 	vingo.Mkdir("model")
+	if len(tableNames) == 0 {
+		tableNames = s.matchTables(option.Include, option.Exclude)
+	}
 	for _, tableName := range tableNames {
 
 		var modelPath = filepath.Join(".", "model", tableName+".go")
@@ -78,38 +94,22 @@ func (s *DbApi) CreateDbModel(tableNames ...string) (bool, error) {
 			continue
 		}
 
-		var tableComment sql.NullString
-		queryTableComment := `
-	SELECT obj_description(c.oid, 'pg_class') 
-	FROM pg_class c
-	WHERE relname = ?
-`
-		s.DB.Raw(queryTableComment, tableName).Scan(&tableComment)
-
-		commentStr := ""
-		if tableComment.Valid {
-			commentStr = tableComment.String
+		database, err := s.Schema(tableName)
+		if err != nil {
+			fmt.Println(err)
+			return false, err
 		}
+		var table = database.Table(tableName)
+		if table == nil {
+			fmt.Printf("表%v不存在\n", tableName)
+			continue
+		}
+		commentStr := table.Comment
 
-		var columns []Column
-		queryColumn := `
-			SELECT 
-				a.attname AS field,
-				format_type(a.atttypid, a.atttypmod) AS type,
-				col_description(a.attrelid, a.attnum) AS comment,
-				CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END AS null
-			FROM 
-				pg_attribute a
-			JOIN 
-				pg_class c ON a.attrelid = c.oid
-			JOIN 
-				pg_namespace n ON c.relnamespace = n.oid
-			WHERE 
-				c.relname = ? 
-				AND a.attnum > 0 
-				AND NOT a.attisdropped
-		`
-		s.DB.Raw(queryColumn, tableName).Scan(&columns)
+		var columns = make([]Column, len(table.Columns))
+		for i, item := range table.Columns {
+			columns[i] = Column{Field: item.Field, Type: item.Type, Null: item.Null, Key: item.Key, Comment: item.Comment}
+		}
 
 		columns = vingo.ForEach[Column](columns, func(item Column, index int) Column {
 			typ := item.Type
@@ -130,19 +130,6 @@ func (s *DbApi) CreateDbModel(tableNames ...string) (bool, error) {
 			}
 			item.JsonName = strutil.CamelCase(item.Field)
 			item.DataName = strutil.UpperFirst(item.JsonName)
-
-			// 判断主键
-			var isPrimaryKey string
-			s.DB.Raw(`
-				SELECT a.attname 
-				FROM pg_index i 
-				JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
-				WHERE i.indrelid = ?::regclass AND i.indisprimary
-			`, tableName).Scan(&isPrimaryKey)
-
-			if isPrimaryKey == item.Field {
-				item.Key = "PRI"
-			}
 			return item
 		})
 
@@ -173,3 +160,34 @@ func (s *DbApi) CreateDbModel(tableNames ...string) (bool, error) {
 	}
 	return true, nil
 }
+
+// 获取public下匹配的表名
+func (s *DbApi) matchTables(include []string, exclude []string) []string {
+	var tables []string
+	s.DB.Table("information_schema.tables").Where("table_schema = 'public' AND table_type = 'BASE TABLE'").Order("table_name").Pluck("table_name", &tables)
+	var result = make([]string, 0, len(tables))
+	for _, table := range tables {
+		if len(include) > 0 && !matchPatterns(table, include) {
+			continue
+		}
+		if matchPatterns(table, exclude) {
+			continue
+		}
+		result = append(result, table)
+	}
+	return result
+}
+
+// 表名是否匹配任一模式，模式语法同path.Match
+func matchPatterns(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：pgsql数据库结构对比
+// *****************************************************************************
+
+package pgsql
+
+import (
+	"github.com/lgdzz/vingo-utils-v2/db/schema"
+)
+
+// 对比两个库的结构，当前库为期望结构（如测试库），target为实际结构（如生产库）
+// 差异报告：diff.Report()，建议的ALTER语句：diff.Alter()
+func (s *DbApi) CompareSchema(target *DbApi) (schema.Diff, error) {
+	source, err := s.Schema()
+	if err != nil {
+		return schema.Diff{}, err
+	}
+	actual, err := target.Schema()
+	if err != nil {
+		return schema.Diff{}, err
+	}
+	return schema.Compare(source, actual), nil
+}
+
+// 对比gorm模型与当前库的结构，只对比模型对应的表
+func (s *DbApi) CompareModels(models ...any) (schema.Diff, error) {
+	expected, err := schema.FromModels(s.DB, models...)
+	if err != nil {
+		return schema.Diff{}, err
+	}
+	var tables = make([]string, len(expected.Tables))
+	for i, table := range expected.Tables {
+		tables[i] = table.Name
+	}
+	actual, err := s.Schema(tables...)
+	if err != nil {
+		return schema.Diff{}, err
+	}
+	return schema.Compare(expected, actual, schema.CompareOption{IgnoreExtraTables: true}), nil
+}
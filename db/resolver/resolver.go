@@ -0,0 +1,219 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：读写分离插件，读请求按权重路由到从库，写请求和事务使用主库，定时检查从库健康状态
+// *****************************************************************************
+
+package resolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const primaryKey = "resolver:primary"
+
+// 从库配置，账号密码为空时使用主库配置
+type ReplicaConfig struct {
+	Host     string `yaml:"host" json:"host"`
+	Port     string `yaml:"port" json:"port"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	Weight   int    `yaml:"weight" json:"weight"` // 权重，默认1
+}
+
+// 从库连接
+type Replica struct {
+	Name    string
+	Weight  int
+	Pool    gorm.ConnPool // 执行查询的连接池
+	DB      *sql.DB       // 用于健康检查
+	healthy atomic.Bool
+}
+
+// 是否健康
+func (s *Replica) Healthy() bool {
+	return s.healthy.Load()
+}
+
+type Option struct {
+	HealthInterval time.Duration // 健康检查间隔，默认10秒
+	HealthTimeout  time.Duration // 健康检查超时时间，默认3秒
+}
+
+type Resolver struct {
+	option   Option
+	replicas []*Replica
+	primary  gorm.ConnPool
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// 创建读写分离插件，db.Use(resolver.New(replicas, resolver.Option{}))
+func New(replicas []*Replica, option Option) *Resolver {
+	if option.HealthInterval <= 0 {
+		option.HealthInterval = 10 * time.Second
+	}
+	if option.HealthTimeout <= 0 {
+		option.HealthTimeout = 3 * time.Second
+	}
+	for _, item := range replicas {
+		if item.Weight <= 0 {
+			item.Weight = 1
+		}
+		item.healthy.Store(true)
+	}
+	return &Resolver{option: option, replicas: replicas, stop: make(chan struct{})}
+}
+
+func (s *Resolver) Name() string {
+	return "vingo:resolver"
+}
+
+func (s *Resolver) Initialize(db *gorm.DB) error {
+	s.primary = db.ConnPool
+	for _, err := range []error{
+		db.Callback().Query().Before("gorm:query").Register("resolver:query", s.read),
+		db.Callback().Row().Before("gorm:row").Register("resolver:row", s.read),
+		db.Callback().Create().Before("gorm:create").Register("resolver:create", s.write),
+		db.Callback().Update().Before("gorm:update").Register("resolver:update", s.write),
+		db.Callback().Delete().Before("gorm:delete").Register("resolver:delete", s.write),
+		db.Callback().Raw().Before("gorm:raw").Register("resolver:raw", s.write),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	if len(s.replicas) > 0 {
+		go s.healthCheck()
+	}
+	return nil
+}
+
+// 读请求路由到从库
+func (s *Resolver) read(db *gorm.DB) {
+	var stmt = db.Statement
+	if s.usePrimary(db) {
+		return
+	}
+	// 锁定读必须在主库执行
+	if _, ok := stmt.Clauses["FOR"]; ok {
+		return
+	}
+	// 原生sql只有SELECT语句走从库
+	if stmt.SQL.Len() > 0 {
+		var sql = strings.ToUpper(strings.TrimSpace(stmt.SQL.String()))
+		if !strings.HasPrefix(sql, "SELECT") || strings.Contains(sql, "FOR UPDATE") || strings.Contains(sql, "FOR SHARE") {
+			return
+		}
+	}
+	if replica := s.pick(); replica != nil {
+		stmt.ConnPool = replica.Pool
+	}
+}
+
+// 写请求使用主库，防止复用的查询链仍指向从库
+func (s *Resolver) write(db *gorm.DB) {
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+	for _, item := range s.replicas {
+		if db.Statement.ConnPool == item.Pool {
+			db.Statement.ConnPool = s.primary
+			return
+		}
+	}
+}
+
+func (s *Resolver) usePrimary(db *gorm.DB) bool {
+	// 事务中的查询使用事务连接，db.Connection固定的连接也不切换
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return true
+	}
+	if _, ok := db.Statement.ConnPool.(*sql.Conn); ok {
+		return true
+	}
+	if primary, ok := db.Get(primaryKey); ok && primary == true {
+		return true
+	}
+	return false
+}
+
+// 按权重随机选择一个健康的从库，全部不可用时返回nil（使用主库）
+func (s *Resolver) pick() *Replica {
+	var total int
+	for _, item := range s.replicas {
+		if item.Healthy() {
+			total += item.Weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	var n = rand.IntN(total)
+	for _, item := range s.replicas {
+		if !item.Healthy() {
+			continue
+		}
+		if n < item.Weight {
+			return item
+		}
+		n -= item.Weight
+	}
+	return nil
+}
+
+func (s *Resolver) healthCheck() {
+	ticker := time.NewTicker(s.option.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			for _, item := range s.replicas {
+				s.ping(item)
+			}
+		}
+	}
+}
+
+func (s *Resolver) ping(replica *Replica) {
+	if replica.DB == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.option.HealthTimeout)
+	defer cancel()
+	err := replica.DB.PingContext(ctx)
+	if err != nil && replica.healthy.CompareAndSwap(true, false) {
+		vingo.LogError(fmt.Sprintf("[读写分离]从库%v不可用，已摘除：%v", replica.Name, err.Error()))
+	} else if err == nil && replica.healthy.CompareAndSwap(false, true) {
+		vingo.LogInfo(fmt.Sprintf("[读写分离]从库%v已恢复", replica.Name))
+	}
+}
+
+// 获取从库列表
+func (s *Resolver) Replicas() []*Replica {
+	return s.replicas
+}
+
+// 停止健康检查
+func (s *Resolver) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// 强制使用主库，用于写后立即读的场景
+// 在新会话上设置，避免标记写入db所在的查询链影响后续查询
+func Primary(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{}).Set(primaryKey, true)
+}
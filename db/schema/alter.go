@@ -0,0 +1,175 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：根据结构差异生成建议的DDL语句，删除表、字段和多余索引的语句以注释输出，需人工确认后执行
+// *****************************************************************************
+
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 无需加引号的默认值，如数字、函数、CURRENT_TIMESTAMP
+var rawDefaultRegexp = regexp.MustCompile(`^(-?\d+(\.\d+)?|null|true|false|current_timestamp(\(\d*\))?|.*\(.*\).*|'.*'(::[a-z ]+)?)$`)
+
+// 生成使target结构与source一致的建议语句
+func (s *Diff) Alter() []string {
+	var sqls = make([]string, 0)
+	for _, table := range s.MissingTables {
+		sqls = append(sqls, s.createTable(table))
+	}
+	for _, table := range s.Tables {
+		var name = s.quote(table.Name)
+		for _, column := range table.MissingColumns {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v ADD COLUMN %v;", name, s.columnDefinition(column)))
+		}
+		for _, column := range table.ChangedColumns {
+			sqls = append(sqls, s.modifyColumn(name, column)...)
+		}
+		// 重建索引不会丢失数据，删除与创建需一起执行，否则创建同名索引失败
+		for _, index := range table.ChangedIndexes {
+			sqls = append(sqls, s.dropIndex(table.Name, index.Target))
+			sqls = append(sqls, s.createIndex(table.Name, index.Source))
+		}
+		for _, index := range table.MissingIndexes {
+			sqls = append(sqls, s.createIndex(table.Name, index))
+		}
+		for _, index := range table.ExtraIndexes {
+			sqls = append(sqls, "-- "+s.dropIndex(table.Name, index))
+		}
+		for _, column := range table.ExtraColumns {
+			sqls = append(sqls, fmt.Sprintf("-- ALTER TABLE %v DROP COLUMN %v;", name, s.quote(column.Field)))
+		}
+	}
+	for _, table := range s.ExtraTables {
+		sqls = append(sqls, fmt.Sprintf("-- DROP TABLE %v;", s.quote(table.Name)))
+	}
+	return sqls
+}
+
+func (s *Diff) quote(name string) string {
+	if s.Dialect == DialectPostgres {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (s *Diff) quoteColumns(columns []string) string {
+	var items = make([]string, len(columns))
+	for i, column := range columns {
+		items[i] = s.quote(column)
+	}
+	return strings.Join(items, ", ")
+}
+
+// 字段定义，如：`name` varchar(50) NOT NULL DEFAULT 'guest' COMMENT '名称'
+func (s *Diff) columnDefinition(column Column) string {
+	var columnType = column.Type
+	var defaultValue = column.Default
+	// postgres自增字段的默认值依赖序列，改用serial类型创建
+	if s.Dialect == DialectPostgres && defaultValue != nil && strings.HasPrefix(*defaultValue, "nextval(") {
+		defaultValue = nil
+		switch NormalizeType(DialectPostgres, columnType) {
+		case "bigint":
+			columnType = "bigserial"
+		case "smallint":
+			columnType = "smallserial"
+		default:
+			columnType = "serial"
+		}
+	}
+	var parts = []string{s.quote(column.Field), columnType}
+	if !column.Nullable() {
+		parts = append(parts, "NOT NULL")
+	}
+	if defaultValue != nil {
+		parts = append(parts, "DEFAULT "+quoteDefault(*defaultValue))
+	}
+	if s.Dialect != DialectPostgres {
+		if strings.Contains(strings.ToLower(column.Extra), "auto_increment") {
+			parts = append(parts, "AUTO_INCREMENT")
+		}
+		if column.Comment != "" {
+			parts = append(parts, "COMMENT "+quoteString(column.Comment))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *Diff) modifyColumn(table string, column ColumnDiff) []string {
+	if s.Dialect != DialectPostgres {
+		return []string{fmt.Sprintf("ALTER TABLE %v MODIFY COLUMN %v;", table, s.columnDefinition(column.Source))}
+	}
+	var sqls = make([]string, 0, 2)
+	var name = s.quote(column.Name)
+	if column.Type {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v ALTER COLUMN %v TYPE %v USING %v::%v;", table, name, column.Source.Type, name, column.Source.Type))
+	}
+	if column.Null {
+		if column.Source.Nullable() {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v ALTER COLUMN %v DROP NOT NULL;", table, name))
+		} else {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v ALTER COLUMN %v SET NOT NULL;", table, name))
+		}
+	}
+	return sqls
+}
+
+func (s *Diff) createTable(table Table) string {
+	var lines = make([]string, 0, len(table.Columns)+1)
+	for _, column := range table.Columns {
+		lines = append(lines, "  "+s.columnDefinition(column))
+	}
+	if pk := table.PrimaryKey(); pk != nil {
+		lines = append(lines, "  PRIMARY KEY ("+s.quoteColumns(pk.Columns)+")")
+	}
+	var sql = fmt.Sprintf("CREATE TABLE %v (\n%v\n)", s.quote(table.Name), strings.Join(lines, ",\n"))
+	if s.Dialect != DialectPostgres && table.Comment != "" {
+		sql += " COMMENT=" + quoteString(table.Comment)
+	}
+	sql += ";"
+	for _, index := range table.Indexes {
+		if !index.Primary {
+			sql += "\n" + s.createIndex(table.Name, index)
+		}
+	}
+	return sql
+}
+
+func (s *Diff) createIndex(table string, index Index) string {
+	if index.Primary {
+		return fmt.Sprintf("ALTER TABLE %v ADD PRIMARY KEY (%v);", s.quote(table), s.quoteColumns(index.Columns))
+	}
+	var unique string
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %vINDEX %v ON %v (%v);", unique, s.quote(index.Name), s.quote(table), s.quoteColumns(index.Columns))
+}
+
+func (s *Diff) dropIndex(table string, index Index) string {
+	switch {
+	case index.Primary && s.Dialect == DialectPostgres:
+		return fmt.Sprintf("ALTER TABLE %v DROP CONSTRAINT %v;", s.quote(table), s.quote(index.Name))
+	case index.Primary:
+		return fmt.Sprintf("ALTER TABLE %v DROP PRIMARY KEY;", s.quote(table))
+	case s.Dialect == DialectPostgres:
+		return fmt.Sprintf("DROP INDEX %v;", s.quote(index.Name))
+	default:
+		return fmt.Sprintf("DROP INDEX %v ON %v;", s.quote(index.Name), s.quote(table))
+	}
+}
+
+func quoteDefault(value string) string {
+	if rawDefaultRegexp.MatchString(strings.ToLower(value)) {
+		return value
+	}
+	return quoteString(value)
+}
+
+func quoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,190 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据库结构对比，以source为期望结构，输出target的差异
+// *****************************************************************************
+
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Diff struct {
+	Source        string      // 期望结构来源，如库名、models
+	Target        string      // 实际结构来源
+	Dialect       string      // 生成ALTER语句使用的数据库类型，取target的类型
+	MissingTables []Table     // target缺少的表
+	ExtraTables   []Table     // target多出的表
+	Tables        []TableDiff // 两边都存在但有差异的表
+}
+
+type TableDiff struct {
+	Name           string
+	MissingColumns []Column // target缺少的字段
+	ExtraColumns   []Column // target多出的字段
+	ChangedColumns []ColumnDiff
+	MissingIndexes []Index // target缺少的索引
+	ExtraIndexes   []Index // target多出的索引
+	ChangedIndexes []IndexDiff
+}
+
+type ColumnDiff struct {
+	Name   string
+	Source Column
+	Target Column
+	Type   bool // 类型不同
+	Null   bool // 是否允许空值不同
+}
+
+type IndexDiff struct {
+	Name   string
+	Source Index
+	Target Index
+}
+
+type CompareOption struct {
+	IgnoreExtraTables bool // 不报告target多出的表，用于模型与数据库对比
+}
+
+// 是否有差异
+func (s *Diff) HasChange() bool {
+	return len(s.MissingTables) > 0 || len(s.ExtraTables) > 0 || len(s.Tables) > 0
+}
+
+func (s *TableDiff) hasChange() bool {
+	return len(s.MissingColumns) > 0 || len(s.ExtraColumns) > 0 || len(s.ChangedColumns) > 0 ||
+		len(s.MissingIndexes) > 0 || len(s.ExtraIndexes) > 0 || len(s.ChangedIndexes) > 0
+}
+
+// 对比两个数据库结构，source为期望结构，target为实际结构
+// 两边数据库类型不同时只对比表、字段和索引是否存在，不对比字段类型
+func Compare(source Database, target Database, option ...CompareOption) Diff {
+	var opt CompareOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	var diff = Diff{Source: source.Name, Target: target.Name, Dialect: target.Dialect}
+	var sameDialect = source.Dialect == target.Dialect
+
+	for _, table := range source.Tables {
+		var other = target.Table(table.Name)
+		if other == nil {
+			diff.MissingTables = append(diff.MissingTables, table)
+			continue
+		}
+		if item := compareTable(table, *other, sameDialect, target.Dialect); item.hasChange() {
+			diff.Tables = append(diff.Tables, item)
+		}
+	}
+	if !opt.IgnoreExtraTables {
+		for _, table := range target.Tables {
+			if source.Table(table.Name) == nil {
+				diff.ExtraTables = append(diff.ExtraTables, table)
+			}
+		}
+	}
+	return diff
+}
+
+func compareTable(source Table, target Table, sameDialect bool, dialect string) TableDiff {
+	var diff = TableDiff{Name: source.Name}
+	for _, column := range source.Columns {
+		var other = target.Column(column.Field)
+		if other == nil {
+			diff.MissingColumns = append(diff.MissingColumns, column)
+			continue
+		}
+		var item = ColumnDiff{Name: column.Field, Source: column, Target: *other}
+		item.Type = sameDialect && NormalizeType(dialect, column.Type) != NormalizeType(dialect, other.Type)
+		item.Null = column.Nullable() != other.Nullable()
+		if item.Type || item.Null {
+			diff.ChangedColumns = append(diff.ChangedColumns, item)
+		}
+	}
+	for _, column := range target.Columns {
+		if source.Column(column.Field) == nil {
+			diff.ExtraColumns = append(diff.ExtraColumns, column)
+		}
+	}
+
+	for _, index := range source.Indexes {
+		var other = findIndex(target.Indexes, index)
+		if other == nil {
+			diff.MissingIndexes = append(diff.MissingIndexes, index)
+			continue
+		}
+		if index.Unique != other.Unique || !slices.Equal(index.Columns, other.Columns) {
+			diff.ChangedIndexes = append(diff.ChangedIndexes, IndexDiff{Name: other.Name, Source: index, Target: *other})
+		}
+	}
+	for _, index := range target.Indexes {
+		if findIndex(source.Indexes, index) == nil {
+			diff.ExtraIndexes = append(diff.ExtraIndexes, index)
+		}
+	}
+	return diff
+}
+
+// 查找对应索引，主键按类型匹配（各数据库主键名称不同），其他按名称匹配
+func findIndex(indexes []Index, index Index) *Index {
+	for i := range indexes {
+		if index.Primary && indexes[i].Primary || !index.Primary && !indexes[i].Primary && indexes[i].Name == index.Name {
+			return &indexes[i]
+		}
+	}
+	return nil
+}
+
+// 文本差异报告
+func (s *Diff) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "结构对比：%v => %v\n", s.Source, s.Target)
+	if !s.HasChange() {
+		b.WriteString("结构一致\n")
+		return b.String()
+	}
+	for _, table := range s.MissingTables {
+		fmt.Fprintf(&b, "+ 表 %v 缺少\n", table.Name)
+	}
+	for _, table := range s.ExtraTables {
+		fmt.Fprintf(&b, "- 表 %v 多余\n", table.Name)
+	}
+	for _, table := range s.Tables {
+		fmt.Fprintf(&b, "~ 表 %v\n", table.Name)
+		for _, column := range table.MissingColumns {
+			fmt.Fprintf(&b, "    + 字段 %v %v 缺少\n", column.Field, column.Type)
+		}
+		for _, column := range table.ExtraColumns {
+			fmt.Fprintf(&b, "    - 字段 %v %v 多余\n", column.Field, column.Type)
+		}
+		for _, column := range table.ChangedColumns {
+			if column.Type {
+				fmt.Fprintf(&b, "    ~ 字段 %v 类型：%v => %v\n", column.Name, column.Target.Type, column.Source.Type)
+			}
+			if column.Null {
+				fmt.Fprintf(&b, "    ~ 字段 %v 允许空值：%v => %v\n", column.Name, column.Target.Null, column.Source.Null)
+			}
+		}
+		for _, index := range table.MissingIndexes {
+			fmt.Fprintf(&b, "    + 索引 %v(%v) 缺少\n", index.Name, strings.Join(index.Columns, ","))
+		}
+		for _, index := range table.ExtraIndexes {
+			fmt.Fprintf(&b, "    - 索引 %v(%v) 多余\n", index.Name, strings.Join(index.Columns, ","))
+		}
+		for _, index := range table.ChangedIndexes {
+			fmt.Fprintf(&b, "    ~ 索引 %v：%v => %v\n", index.Name, indexText(index.Target), indexText(index.Source))
+		}
+	}
+	return b.String()
+}
+
+func indexText(index Index) string {
+	var text = "(" + strings.Join(index.Columns, ",") + ")"
+	if index.Unique && !index.Primary {
+		text = "UNIQUE" + text
+	}
+	return text
+}
@@ -0,0 +1,93 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：根据gorm模型生成期望的数据库结构，用于模型与数据库对比
+// *****************************************************************************
+
+package schema
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	gormSchema "gorm.io/gorm/schema"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var autoIncrementRegexp = regexp.MustCompile(`(?i)\s*auto_increment`)
+
+// 解析gorm模型，字段类型按db的数据库类型生成
+func FromModels(db *gorm.DB, models ...any) (Database, error) {
+	var database = Database{Name: "models", Dialect: db.Dialector.Name()}
+	for _, model := range models {
+		var stmt = &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return database, fmt.Errorf("解析模型%T错误：%w", model, err)
+		}
+		database.Tables = append(database.Tables, modelTable(db, stmt.Schema))
+	}
+	return database, nil
+}
+
+func modelTable(db *gorm.DB, sch *gormSchema.Schema) Table {
+	var table = Table{Name: sch.Table}
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.IgnoreMigration {
+			continue
+		}
+		var column = Column{
+			Field:   field.DBName,
+			Type:    db.Dialector.DataTypeOf(field),
+			Null:    "YES",
+			Comment: field.Comment,
+		}
+		if field.NotNull || field.PrimaryKey {
+			column.Null = "NO"
+		}
+		if field.PrimaryKey {
+			column.Key = "PRI"
+		}
+		if field.AutoIncrement {
+			column.Extra = "auto_increment"
+			// mysql的DataTypeOf会带上AUTO_INCREMENT，与数据库中的字段类型不一致
+			column.Type = strings.TrimSpace(autoIncrementRegexp.ReplaceAllString(column.Type, ""))
+		}
+		if field.HasDefaultValue && field.DefaultValue != "" {
+			var value = field.DefaultValue
+			column.Default = &value
+		}
+		table.Columns = append(table.Columns, column)
+	}
+
+	if len(sch.PrimaryFieldDBNames) > 0 {
+		table.Indexes = append(table.Indexes, Index{Name: "PRIMARY", Columns: sch.PrimaryFieldDBNames, Unique: true, Primary: true})
+	}
+	var indexes = sch.ParseIndexes()
+	var names = make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var index = indexes[name]
+		var item = Index{Name: index.Name, Unique: strings.EqualFold(index.Class, "UNIQUE")}
+		for _, option := range index.Fields {
+			if option.Field != nil {
+				item.Columns = append(item.Columns, option.DBName)
+			}
+		}
+		table.Indexes = append(table.Indexes, item)
+	}
+	// unique标签生成的唯一约束，数据库中表现为唯一索引
+	var uniques = sch.ParseUniqueConstraints()
+	names = names[:0]
+	for name := range uniques {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		table.Indexes = append(table.Indexes, Index{Name: name, Columns: []string{uniques[name].Field.DBName}, Unique: true})
+	}
+	return table
+}
@@ -0,0 +1,179 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：数据库结构，由各数据库驱动读取，用于数据字典、模型生成和结构对比
+// *****************************************************************************
+
+package schema
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 数据库类型，与gorm Dialector.Name()一致
+const (
+	DialectMysql    = "mysql"
+	DialectPostgres = "postgres"
+)
+
+type Database struct {
+	Name        string  `json:"name"`
+	Dialect     string  `json:"dialect"`
+	ReleaseTime string  `json:"releaseTime"`
+	Tables      []Table `json:"tables"`
+}
+
+type Table struct {
+	Name        string       `json:"name"`
+	Comment     string       `json:"comment"`
+	Columns     []Column     `json:"columns"`
+	Indexes     []Index      `json:"indexes"`
+	ForeignKeys []ForeignKey `json:"foreignKeys"`
+}
+
+type Column struct {
+	Field   string  `json:"field"`
+	Type    string  `json:"type"`
+	Null    string  `json:"null"` // YES|NO
+	Key     string  `json:"key"`
+	Default *string `json:"default"` // nil表示无默认值
+	Extra   string  `json:"extra"`
+	Comment string  `json:"comment"`
+}
+
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+}
+
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"refTable"`
+	RefColumns []string `json:"refColumns"`
+	OnUpdate   string   `json:"onUpdate"`
+	OnDelete   string   `json:"onDelete"`
+}
+
+// 按表名查找，不存在时返回nil
+func (s *Database) Table(name string) *Table {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// 按字段名查找，不存在时返回nil
+func (s *Table) Column(name string) *Column {
+	for i := range s.Columns {
+		if s.Columns[i].Field == name {
+			return &s.Columns[i]
+		}
+	}
+	return nil
+}
+
+// 主键索引，不存在时返回nil
+func (s *Table) PrimaryKey() *Index {
+	for i := range s.Indexes {
+		if s.Indexes[i].Primary {
+			return &s.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// 是否允许空值
+func (s *Column) Nullable() bool {
+	return s.Null == "YES"
+}
+
+// 追加索引字段，查询结果按索引名、字段顺序排序时，同名索引合并为一条
+func AppendIndex(indexes []Index, name string, column string, unique bool, primary bool) []Index {
+	if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+		indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+		return indexes
+	}
+	return append(indexes, Index{Name: name, Columns: []string{column}, Unique: unique, Primary: primary})
+}
+
+// 追加外键字段，查询结果按外键名、字段顺序排序时，同名外键合并为一条
+func AppendForeignKey(foreignKeys []ForeignKey, name string, column string, refTable string, refColumn string, onUpdate string, onDelete string) []ForeignKey {
+	if n := len(foreignKeys); n > 0 && foreignKeys[n-1].Name == name {
+		foreignKeys[n-1].Columns = append(foreignKeys[n-1].Columns, column)
+		foreignKeys[n-1].RefColumns = append(foreignKeys[n-1].RefColumns, refColumn)
+		return foreignKeys
+	}
+	return append(foreignKeys, ForeignKey{
+		Name:       name,
+		Columns:    []string{column},
+		RefTable:   refTable,
+		RefColumns: []string{refColumn},
+		OnUpdate:   onUpdate,
+		OnDelete:   onDelete,
+	})
+}
+
+var (
+	// mysql整数类型的显示宽度，8.0起不再返回，对比时忽略
+	intWidthRegexp = regexp.MustCompile(`^(bigint|int|mediumint|smallint|tinyint)\(\d+\)`)
+	spaceRegexp    = regexp.MustCompile(`\s+`)
+	argsRegexp     = regexp.MustCompile(`\(\s*\d+(\s*,\s*\d+)?\s*\)`)
+)
+
+// postgres类型别名
+var postgresTypes = map[string]string{
+	"int8":        "bigint",
+	"bigserial":   "bigint",
+	"int":         "integer",
+	"int4":        "integer",
+	"serial":      "integer",
+	"int2":        "smallint",
+	"smallserial": "smallint",
+	"bool":        "boolean",
+	"float8":      "double precision",
+	"float4":      "real",
+	"decimal":     "numeric",
+	"varchar":     "character varying",
+	"char":        "character",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+}
+
+// 统一字段类型写法，用于对比
+func NormalizeType(dialect string, value string) string {
+	value = spaceRegexp.ReplaceAllString(strings.ToLower(strings.TrimSpace(value)), " ")
+	switch dialect {
+	case DialectMysql:
+		if value == "bool" || value == "boolean" {
+			return "tinyint(1)"
+		}
+		// tinyint(1)表示布尔，保留
+		if !strings.HasPrefix(value, "tinyint(1)") {
+			value = intWidthRegexp.ReplaceAllString(value, "$1")
+		}
+		value = strings.Replace(value, "integer", "int", 1)
+	case DialectPostgres:
+		// 精度参数单独取出，如：timestamp(3) with time zone、numeric(10,2)
+		var args = argsRegexp.FindString(value)
+		if args != "" {
+			value = spaceRegexp.ReplaceAllString(strings.TrimSpace(strings.Replace(value, args, "", 1)), " ")
+			args = strings.ReplaceAll(args, " ", "")
+		}
+		if alias, ok := postgresTypes[value]; ok {
+			value = alias
+		}
+		if i := strings.Index(value, " with"); i > 0 && args != "" {
+			return value[:i] + args + value[i:]
+		}
+		value += args
+	}
+	return value
+}
@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"errors"
 	"fmt"
 	"github.com/lgdzz/vingo-utils-exception/exception"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
@@ -44,6 +45,7 @@ func InitSqliteService() {
 	RegisterAfterUpdate(db)
 	RegisterAfterDelete(db)
 
+	// 需要乐观锁时自行注册：sqlite.Db.Use(version.New())
 	Db = db
 }
 
@@ -71,6 +73,9 @@ func RegisterAfterCreate(db *gorm.DB) {
 
 func RegisterAfterUpdate(db *gorm.DB) {
 	err := db.Callback().Update().After("gorm:update").Register("gormerror:after_update", func(db *gorm.DB) {
+		if conflict := new(vingo.ConflictException); errors.As(db.Error, &conflict) {
+			panic(conflict)
+		}
 		if db.Error != nil {
 			panic(&exception.DbException{Message: db.Error.Error()})
 		}
@@ -0,0 +1,238 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：多租户数据隔离插件，按请求的数据维度自动追加单位/部门/账户条件
+// *****************************************************************************
+
+package tenant
+
+import (
+	"context"
+	"errors"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"sync"
+)
+
+// 数据维度，与vingo.DataDimension一致
+const (
+	DimensionAcc  = 1 // 本账户
+	DimensionDept = 2 // 本部门
+	DimensionOrg  = 3 // 本单位
+	DimensionMax  = 4 // 本单位至下属单位
+)
+
+const (
+	unscopedKey   = "tenant:unscoped"
+	enabledClause = "tenant_enabled" // 已追加隔离条件的标记，避免复用同一链式查询时重复追加
+)
+
+// 需要数据隔离的模型未携带数据范围时返回的错误
+var ErrMissingScope = errors.New("缺少数据权限范围，请使用tenant.Context(c)或tenant.Unscoped(db)")
+
+// 模型中的数据隔离字段，为空的字段不生成条件
+type Columns struct {
+	Org  string // 单位字段，如org_id
+	Dept string // 部门字段，如dept_id
+	Acc  string // 账户字段，如acc_id
+}
+
+// 需要数据隔离的模型实现此接口
+//
+//	func (s *Article) TenantColumns() tenant.Columns {
+//		return tenant.Columns{Org: "org_id", Dept: "dept_id", Acc: "acc_id"}
+//	}
+type Model interface {
+	TenantColumns() Columns
+}
+
+// 当前请求的数据范围
+type Scope struct {
+	OrgId         int
+	DeptId        int
+	AccId         int
+	DataDimension int
+
+	once   sync.Once
+	orgIds []int
+}
+
+// 从请求上下文读取数据范围
+func NewScope(c *vingo.Context) *Scope {
+	return &Scope{
+		OrgId:         c.GetOrgId(),
+		DeptId:        c.GetDeptId(),
+		AccId:         c.GetAccId(),
+		DataDimension: c.GetDataDimension(),
+	}
+}
+
+type scopeKey struct{}
+
+// 将数据范围写入context
+func WithScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// 读取context中的数据范围
+func FromContext(ctx context.Context) (*Scope, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	scope, ok := ctx.Value(scopeKey{}).(*Scope)
+	return scope, ok && scope != nil
+}
+
+// 生成携带当前请求数据范围的context，用于db.WithContext
+func Context(c *vingo.Context) context.Context {
+	return WithScope(c.Request.Context(), NewScope(c))
+}
+
+// 跳过数据隔离，用于后台任务或需要跨单位查询的场景
+func Unscoped(db *gorm.DB) *gorm.DB {
+	return db.Set(unscopedKey, true)
+}
+
+type Config struct {
+	AllowEmpty bool                  // 为true时未携带数据范围的查询不做限制，默认返回ErrMissingScope
+	SubOrgIds  func(orgId int) []int // 获取本单位及下属单位ID，用于本单位至下属单位维度，未设置时按本单位处理
+}
+
+type Plugin struct {
+	Config Config
+}
+
+// 创建数据隔离插件，db.Use(tenant.New(tenant.Config{}))
+func New(config Config) *Plugin {
+	return &Plugin{Config: config}
+}
+
+func (s *Plugin) Name() string {
+	return "vingo:tenant"
+}
+
+func (s *Plugin) Initialize(db *gorm.DB) error {
+	return errors.Join(
+		db.Callback().Query().Before("gorm:query").Register("tenant:query", s.query),
+		db.Callback().Row().Before("gorm:row").Register("tenant:row", s.query),
+		db.Callback().Update().Before("gorm:update").Register("tenant:update", s.modify),
+		db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", s.modify),
+	)
+}
+
+func (s *Plugin) query(db *gorm.DB) {
+	s.apply(db, false)
+}
+
+func (s *Plugin) modify(db *gorm.DB) {
+	s.apply(db, true)
+}
+
+func (s *Plugin) apply(db *gorm.DB, modify bool) {
+	var stmt = db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return
+	}
+	if _, ok := stmt.Clauses[enabledClause]; ok {
+		return
+	}
+	if unscoped, ok := db.Get(unscopedKey); ok && unscoped == true {
+		return
+	}
+	model, ok := reflect.New(stmt.Schema.ModelType).Interface().(Model)
+	if !ok {
+		return
+	}
+	scope, ok := FromContext(stmt.Context)
+	if !ok {
+		if !s.Config.AllowEmpty {
+			_ = db.AddError(ErrMissingScope)
+		}
+		return
+	}
+	// 没有条件的批量更新/删除交由gorm拦截，不因追加隔离条件而放行
+	if modify && !hasCondition(stmt) {
+		return
+	}
+	var exprs = s.conditions(model.TenantColumns(), scope)
+	if len(exprs) == 0 {
+		return
+	}
+
+	// 已有条件整体加括号，避免其中的OR与隔离条件的优先级错误
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			if orCond, ok := where.Exprs[0].(clause.OrConditions); ok && len(where.Exprs) == 1 {
+				where.Exprs[0] = clause.AndConditions(orCond)
+			} else if len(where.Exprs) > 1 {
+				where.Exprs = []clause.Expression{clause.AndConditions{Exprs: where.Exprs}}
+			}
+			c.Expression = where
+			stmt.Clauses["WHERE"] = c
+		}
+	}
+	stmt.AddClause(clause.Where{Exprs: exprs})
+	stmt.Clauses[enabledClause] = clause.Clause{}
+}
+
+// 按数据维度生成条件，未知维度按本单位处理
+func (s *Plugin) conditions(columns Columns, scope *Scope) []clause.Expression {
+	var exprs = make([]clause.Expression, 0, 2)
+	if columns.Org != "" {
+		if scope.DataDimension == DimensionMax && s.Config.SubOrgIds != nil {
+			scope.once.Do(func() {
+				scope.orgIds = s.Config.SubOrgIds(scope.OrgId)
+			})
+			exprs = append(exprs, clause.IN{Column: column(columns.Org), Values: toValues(append([]int{scope.OrgId}, scope.orgIds...))})
+		} else {
+			exprs = append(exprs, clause.Eq{Column: column(columns.Org), Value: scope.OrgId})
+		}
+	}
+	switch scope.DataDimension {
+	case DimensionDept:
+		if columns.Dept != "" {
+			exprs = append(exprs, clause.Eq{Column: column(columns.Dept), Value: scope.DeptId})
+		}
+	case DimensionAcc:
+		if columns.Acc != "" {
+			exprs = append(exprs, clause.Eq{Column: column(columns.Acc), Value: scope.AccId})
+		}
+	}
+	return exprs
+}
+
+func column(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+func toValues(ids []int) []any {
+	var values = make([]any, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id)
+	}
+	return values
+}
+
+// 是否已有条件（where条件、主键值或允许全局更新）
+func hasCondition(stmt *gorm.Statement) bool {
+	if _, ok := stmt.Clauses["WHERE"]; ok {
+		return true
+	}
+	if stmt.AllowGlobalUpdate {
+		return true
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return false
+	}
+	var value = reflect.Indirect(stmt.ReflectValue)
+	switch value.Kind() {
+	case reflect.Struct:
+		_, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, value)
+		return !zero
+	case reflect.Slice, reflect.Array:
+		return value.Len() > 0
+	}
+	return false
+}
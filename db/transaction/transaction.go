@@ -0,0 +1,173 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：通过context传递的事务，嵌套调用时以保存点执行，支持隔离级别、只读、死锁重试和提交后回调
+// *****************************************************************************
+
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-exception/exception"
+	"gorm.io/gorm"
+	"time"
+)
+
+// 重试前等待时长，按重试次数递增
+var RetryDelay = 50 * time.Millisecond
+
+type Option struct {
+	Isolation sql.IsolationLevel // 隔离级别，默认使用数据库设置
+	ReadOnly  bool               // 只读事务
+	Retry     int                // 死锁或序列化失败时的重试次数，重试会重新执行整个handler
+}
+
+// 判断错误是否可以重试事务，由各数据库驱动提供
+type Retryable func(err error) bool
+
+// 进行中的事务，同一事务不能在多个goroutine中并发使用
+type state struct {
+	tx        *gorm.DB
+	savepoint int      // 保存点序号，用于生成不重复的保存点名称
+	hooks     []func() // 提交后执行的函数
+	done      bool     // 事务已结束，持有此context的后续调用开启新事务
+}
+
+type stateKey struct{}
+
+func fromContext(ctx context.Context) *state {
+	if ctx == nil {
+		return nil
+	}
+	st, ok := ctx.Value(stateKey{}).(*state)
+	if !ok || st.done {
+		return nil
+	}
+	return st
+}
+
+// ctx中是否有进行中的事务
+func InTransaction(ctx context.Context) bool {
+	return fromContext(ctx) != nil
+}
+
+// ctx中有进行中的事务时返回该事务，否则返回db
+func DB(db *gorm.DB, ctx context.Context) *gorm.DB {
+	if st := fromContext(ctx); st != nil {
+		return st.tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// 注册事务提交后执行的函数，如发送消息、清理缓存
+// 仅在最外层事务提交后执行，事务或所在的保存点回滚时丢弃；tx不在事务中时立即执行
+func AfterCommit(tx *gorm.DB, fn func()) {
+	if st := fromContext(tx.Statement.Context); st != nil {
+		st.hooks = append(st.hooks, fn)
+		return
+	}
+	fn()
+}
+
+// 执行事务，handler中panic或出错时回滚并将异常往外抛
+// db的context中已有事务时加入该事务，以保存点执行handler，此时option不生效
+func Run(db *gorm.DB, handler func(*gorm.DB), retryable Retryable, option ...Option) {
+	if st := fromContext(db.Statement.Context); st != nil {
+		nested(st, db.Statement.Context, handler)
+		return
+	}
+	var opt Option
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	for attempt := 0; ; attempt++ {
+		hooks, failure := run(db, handler, opt)
+		if failure == nil {
+			for _, hook := range hooks {
+				hook()
+			}
+			return
+		}
+		if attempt >= opt.Retry || retryable == nil || !retryable(toError(failure)) {
+			panic(failure)
+		}
+		time.Sleep(time.Duration(attempt+1) * RetryDelay)
+	}
+}
+
+// 执行一次最外层事务，失败时返回异常
+func run(db *gorm.DB, handler func(*gorm.DB), opt Option) (hooks []func(), failure any) {
+	var tx *gorm.DB
+	if opt.Isolation != sql.LevelDefault || opt.ReadOnly {
+		tx = db.Begin(&sql.TxOptions{Isolation: opt.Isolation, ReadOnly: opt.ReadOnly})
+	} else {
+		tx = db.Begin()
+	}
+	if tx.Error != nil {
+		return nil, &exception.DbException{Message: tx.Error.Error()}
+	}
+	var st = &state{}
+	st.tx = tx.WithContext(context.WithValue(tx.Statement.Context, stateKey{}, st))
+	defer func() {
+		st.done = true
+		if r := recover(); r != nil {
+			tx.Rollback()
+			failure = r
+		}
+	}()
+
+	handler(st.tx)
+	if err := st.tx.Error; err != nil {
+		panic(&exception.DbException{Message: err.Error()})
+	}
+	if err := tx.Commit().Error; err != nil {
+		panic(&exception.DbException{Message: err.Error()})
+	}
+	return st.hooks, nil
+}
+
+// 以保存点执行，出错时回滚到保存点并将异常往外抛，由外层决定是否继续
+func nested(st *state, ctx context.Context, handler func(*gorm.DB)) {
+	st.savepoint++
+	var name = fmt.Sprintf("vingo_sp%d", st.savepoint)
+	var hooks = len(st.hooks)
+	if err := st.tx.Session(&gorm.Session{}).SavePoint(name).Error; err != nil {
+		panic(&exception.DbException{Message: err.Error()})
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			st.tx.Session(&gorm.Session{}).RollbackTo(name)
+			st.hooks = st.hooks[:hooks]
+			panic(r)
+		}
+	}()
+
+	handler(st.tx.WithContext(ctx))
+	if err := release(st.tx, name); err != nil {
+		panic(&exception.DbException{Message: err.Error()})
+	}
+}
+
+// 释放保存点，与SavePoint一致不使用预处理语句
+func release(tx *gorm.DB, name string) error {
+	var session = tx.Session(&gorm.Session{})
+	if pool, ok := session.Statement.ConnPool.(*gorm.PreparedStmtTX); ok {
+		session.Statement.ConnPool = pool.Tx
+	}
+	return session.Exec("RELEASE SAVEPOINT " + name).Error
+}
+
+func toError(value any) error {
+	switch t := value.(type) {
+	case *exception.DbException:
+		return errors.New(t.Message)
+	case error:
+		return t
+	case string:
+		return errors.New(t)
+	}
+	return fmt.Errorf("%v", value)
+}
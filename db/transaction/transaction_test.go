@@ -0,0 +1,172 @@
+package transaction
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lgdzz/vingo-utils-exception/exception"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type row struct {
+	Id   uint
+	Name string
+}
+
+var errDeadlock = errors.New("deadlock")
+
+func isDeadlock(err error) bool {
+	return err.Error() == errDeadlock.Error()
+}
+
+func openDb(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&row{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func names(db *gorm.DB) (result []string) {
+	db.Model(&row{}).Order("id").Pluck("name", &result)
+	return
+}
+
+// 执行handler并返回panic的值
+func catch(handler func()) (failure any) {
+	defer func() {
+		failure = recover()
+	}()
+	handler()
+	return
+}
+
+// 内层保存点回滚后外层事务继续执行并提交
+func TestNestedRollback(t *testing.T) {
+	var db = openDb(t)
+	Run(db, func(tx *gorm.DB) {
+		tx.Create(&row{Name: "a"})
+		var failure = catch(func() {
+			Run(tx, func(tx *gorm.DB) {
+				tx.Create(&row{Name: "b"})
+				panic("内层失败")
+			}, nil)
+		})
+		if failure != "内层失败" {
+			t.Fatalf("内层异常未往外抛：%v", failure)
+		}
+		Run(tx, func(tx *gorm.DB) {
+			tx.Create(&row{Name: "c"})
+		}, nil)
+	}, nil)
+	if result := names(db); len(result) != 2 || result[0] != "a" || result[1] != "c" {
+		t.Fatalf("保存点回滚错误：%v", result)
+	}
+}
+
+// 外层失败时内层已释放的保存点一并回滚
+func TestOuterRollback(t *testing.T) {
+	var db = openDb(t)
+	var failure = catch(func() {
+		Run(db, func(tx *gorm.DB) {
+			Run(tx, func(tx *gorm.DB) {
+				tx.Create(&row{Name: "a"})
+			}, nil)
+			_ = tx.AddError(errors.New("外层失败"))
+		}, nil)
+	})
+	if e, ok := failure.(*exception.DbException); !ok || e.Message != "外层失败" {
+		t.Fatalf("未抛出DbException：%#v", failure)
+	}
+	if result := names(db); len(result) != 0 {
+		t.Fatalf("事务未回滚：%v", result)
+	}
+}
+
+// 提交后回调仅在最外层提交后执行，回滚的保存点中注册的回调丢弃
+func TestAfterCommit(t *testing.T) {
+	var db = openDb(t)
+	var calls []string
+	Run(db, func(tx *gorm.DB) {
+		AfterCommit(tx, func() { calls = append(calls, "outer") })
+		Run(tx, func(tx *gorm.DB) {
+			AfterCommit(tx, func() { calls = append(calls, "inner") })
+		}, nil)
+		catch(func() {
+			Run(tx, func(tx *gorm.DB) {
+				AfterCommit(tx, func() { calls = append(calls, "rollback") })
+				panic("内层失败")
+			}, nil)
+		})
+		if len(calls) != 0 {
+			t.Fatalf("提交前执行了回调：%v", calls)
+		}
+	}, nil)
+	if len(calls) != 2 || calls[0] != "outer" || calls[1] != "inner" {
+		t.Fatalf("提交后回调错误：%v", calls)
+	}
+
+	calls = nil
+	catch(func() {
+		Run(db, func(tx *gorm.DB) {
+			AfterCommit(tx, func() { calls = append(calls, "outer") })
+			panic("外层失败")
+		}, nil)
+	})
+	if len(calls) != 0 {
+		t.Fatalf("回滚后执行了回调：%v", calls)
+	}
+
+	AfterCommit(db, func() { calls = append(calls, "direct") })
+	if len(calls) != 1 {
+		t.Fatalf("不在事务中时未立即执行：%v", calls)
+	}
+}
+
+// 仅可重试的错误重新执行整个handler，重试次数用尽后往外抛
+func TestRetry(t *testing.T) {
+	RetryDelay = 0
+	var db = openDb(t)
+	var attempts int
+	Run(db, func(tx *gorm.DB) {
+		attempts++
+		tx.Create(&row{Name: "a"})
+		if attempts < 3 {
+			panic(errDeadlock)
+		}
+	}, isDeadlock, Option{Retry: 2})
+	if attempts != 3 {
+		t.Fatalf("重试次数错误：%v", attempts)
+	}
+	if result := names(db); len(result) != 1 {
+		t.Fatalf("失败的尝试未回滚：%v", result)
+	}
+
+	attempts = 0
+	var failure = catch(func() {
+		Run(db, func(tx *gorm.DB) {
+			attempts++
+			panic(errDeadlock)
+		}, isDeadlock, Option{Retry: 2})
+	})
+	if failure != errDeadlock || attempts != 3 {
+		t.Fatalf("重试用尽后错误：%v %v", failure, attempts)
+	}
+
+	attempts = 0
+	failure = catch(func() {
+		Run(db, func(tx *gorm.DB) {
+			attempts++
+			panic("业务错误")
+		}, isDeadlock, Option{Retry: 2})
+	})
+	if failure != "业务错误" || attempts != 1 {
+		t.Fatalf("不可重试的错误被重试：%v %v", failure, attempts)
+	}
+}
@@ -0,0 +1,249 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：乐观锁插件，更新时校验版本号并自增，数据已被他人修改时返回vingo.ConflictException
+// *****************************************************************************
+
+package version
+
+import (
+	"errors"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	skipKey     = "version:skip"
+	checkedKey  = "version:checked" // 本次更新校验的版本号
+	assignedKey = "version:assigned"
+)
+
+// 版本号校验失败的提示
+var ConflictMessage = "数据已被他人修改，请刷新后重试"
+
+// 判断是否为版本冲突错误
+func IsConflict(err error) bool {
+	var conflict *vingo.ConflictException
+	return errors.As(err, &conflict)
+}
+
+// 跳过版本号校验和自增，用于后台修复数据等场景
+func Skip(db *gorm.DB) *gorm.DB {
+	return db.Set(skipKey, true)
+}
+
+type Plugin struct {
+	fields sync.Map // *schema.Schema => *schema.Field，无版本号字段时为nil
+}
+
+// 创建乐观锁插件，db.Use(version.New())，mysql、pgsql配置OptimisticLock后自动注册
+// 仅对标记了gorm:"version"的整数字段生效，未标记的version字段按普通字段处理
+//
+//	type Article struct {
+//		Id      uint
+//		Title   string
+//		Version int `gorm:"version"`
+//	}
+//
+// 新建数据时版本号为0则置为1；更新时以模型（或更新数据）中的版本号作为条件，
+// 版本号为0时不校验，仅自增
+func New() *Plugin {
+	return &Plugin{}
+}
+
+func (s *Plugin) Name() string {
+	return "vingo:version"
+}
+
+func (s *Plugin) Initialize(db *gorm.DB) error {
+	return errors.Join(
+		db.Callback().Create().Before("gorm:create").Register("version:before_create", s.beforeCreate),
+		db.Callback().Update().Before("gorm:update").After("tenant:update").Register("version:before_update", s.beforeUpdate),
+		db.Callback().Update().After("gorm:update").Before("gormerror:after_update").Register("version:after_update", s.afterUpdate),
+	)
+}
+
+func (s *Plugin) field(sch *schema.Schema) *schema.Field {
+	if value, ok := s.fields.Load(sch); ok {
+		return value.(*schema.Field)
+	}
+	var found = Field(sch)
+	s.fields.Store(sch, found)
+	return found
+}
+
+// 模型中标记了gorm:"version"的整数字段，没有时返回nil
+func Field(sch *schema.Schema) *schema.Field {
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.DataType != schema.Int && field.DataType != schema.Uint {
+			continue
+		}
+		if _, ok := field.TagSettings["VERSION"]; ok {
+			return field
+		}
+	}
+	return nil
+}
+
+// 校验更新数据携带了版本号，value为模型指针，模型没有版本号字段时不校验
+// 版本号为0时插件不追加版本号条件，接收前端提交的更新前需调用此方法
+func Require(db *gorm.DB, value any) {
+	var stmt = &gorm.Statement{DB: db}
+	if err := stmt.Parse(value); err != nil {
+		panic(err.Error())
+	}
+	var field = Field(stmt.Schema)
+	if field == nil {
+		return
+	}
+	if _, zero := field.ValueOf(db.Statement.Context, reflect.Indirect(reflect.ValueOf(value))); zero {
+		var name = strings.Split(field.Tag.Get("json"), ",")[0]
+		panic(&vingo.ParamException{Field: vingo.SY(name != "" && name != "-", name, field.DBName), Message: "缺少版本号"})
+	}
+}
+
+func (s *Plugin) enabled(db *gorm.DB) *schema.Field {
+	var stmt = db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return nil
+	}
+	if skip, ok := db.Get(skipKey); ok && skip == true {
+		return nil
+	}
+	return s.field(stmt.Schema)
+}
+
+// 新建数据的版本号从1开始
+func (s *Plugin) beforeCreate(db *gorm.DB) {
+	var field = s.enabled(db)
+	if field == nil {
+		return
+	}
+	var stmt = db.Statement
+	var init = func(value reflect.Value) {
+		if _, zero := field.ValueOf(stmt.Context, value); zero {
+			_ = db.AddError(field.Set(stmt.Context, value, 1))
+		}
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		init(stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			init(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+}
+
+// 生成更新字段并替换版本号为自增，单条数据且版本号不为0时追加版本号条件
+func (s *Plugin) beforeUpdate(db *gorm.DB) {
+	var field = s.enabled(db)
+	if field == nil {
+		return
+	}
+	var stmt = db.Statement
+	if _, ok := stmt.Clauses["SET"]; ok {
+		return
+	}
+	// 需在生成更新字段前读取，生成时会将更新数据赋值到模型
+	var current = s.current(stmt, field)
+	var set = callbacks.ConvertToAssignments(stmt)
+	if len(set) == 0 {
+		return
+	}
+	set = withoutColumn(set, field.DBName)
+	set = append(set, clause.Assignment{
+		Column: clause.Column{Name: field.DBName},
+		Value:  gorm.Expr("? + 1", clause.Column{Table: clause.CurrentTable, Name: field.DBName}),
+	})
+	stmt.AddClause(set)
+	db.InstanceSet(assignedKey, true)
+
+	// 没有其他条件时不追加，避免版本号条件放行全表更新
+	if _, ok := stmt.Clauses["WHERE"]; ok && current > 0 {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: current},
+		}})
+		db.InstanceSet(checkedKey, current)
+	}
+}
+
+// 本次更新校验的版本号，更新数据中携带的版本号优先（如前端提交的版本号），其次为模型中的版本号
+func (s *Plugin) current(stmt *gorm.Statement, field *schema.Field) int64 {
+	switch dest := stmt.Dest.(type) {
+	case map[string]any:
+		for _, key := range []string{field.DBName, field.Name} {
+			if value, ok := dest[key]; ok {
+				return toInt64(value)
+			}
+		}
+	default:
+		if stmt.Dest != stmt.Model {
+			var value = reflect.Indirect(reflect.ValueOf(stmt.Dest))
+			if value.Kind() == reflect.Struct && value.Type() == stmt.Schema.ModelType {
+				if version, zero := field.ValueOf(stmt.Context, value); !zero {
+					return toInt64(version)
+				}
+			}
+		}
+	}
+	if stmt.ReflectValue.Kind() != reflect.Struct {
+		return 0
+	}
+	version, _ := field.ValueOf(stmt.Context, stmt.ReflectValue)
+	return toInt64(version)
+}
+
+// 未更新到数据时返回冲突错误，更新成功则同步内存中的版本号
+func (s *Plugin) afterUpdate(db *gorm.DB) {
+	if _, ok := db.InstanceGet(assignedKey); !ok {
+		return
+	}
+	var stmt = db.Statement
+	delete(stmt.Clauses, "SET")
+	value, ok := db.InstanceGet(checkedKey)
+	if !ok || db.Error != nil {
+		return
+	}
+	if db.RowsAffected == 0 {
+		_ = db.AddError(&vingo.ConflictException{Message: ConflictMessage})
+		return
+	}
+	var field = s.field(stmt.Schema)
+	var next = value.(int64) + 1
+	for _, target := range []any{stmt.Model, stmt.Dest} {
+		var item = reflect.Indirect(reflect.ValueOf(target))
+		if item.Kind() == reflect.Struct && item.CanAddr() && item.Type() == stmt.Schema.ModelType {
+			_ = field.Set(stmt.Context, item, next)
+		}
+	}
+}
+
+func withoutColumn(set clause.Set, column string) clause.Set {
+	var items = set[:0]
+	for _, item := range set {
+		if !strings.EqualFold(item.Column.Name, column) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func toInt64(value any) int64 {
+	var v = reflect.Indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float())
+	}
+	return 0
+}
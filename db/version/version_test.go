@@ -0,0 +1,134 @@
+package version
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type lockedRow struct {
+	Id       uint
+	Title    string
+	Revision int `gorm:"version" json:"revision"`
+}
+
+// 名为version但未标记的业务字段
+type plainRow struct {
+	Id      uint
+	Title   string
+	Version int
+}
+
+func openDb(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%v?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Use(New()); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&lockedRow{}, &plainRow{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCreate(t *testing.T) {
+	var db = openDb(t)
+	var rows = []lockedRow{{Title: "a"}, {Title: "b", Revision: 3}}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Revision != 1 || rows[1].Revision != 3 {
+		t.Fatalf("新建版本号错误：%v %v", rows[0].Revision, rows[1].Revision)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	var db = openDb(t)
+	var row = lockedRow{Title: "a"}
+	db.Create(&row)
+
+	if err := db.Model(&row).Updates(lockedRow{Title: "b"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&row).Updates(map[string]any{"title": "c", "revision": 2}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var saved lockedRow
+	db.First(&saved, row.Id)
+	if saved.Title != "c" || saved.Revision != 3 || row.Revision != 3 {
+		t.Fatalf("版本号未自增：%+v %+v", saved, row)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	var db = openDb(t)
+	var row = lockedRow{Title: "a"}
+	db.Create(&row)
+
+	var stale = row
+	db.Model(&row).Updates(lockedRow{Title: "b"})
+	var err = db.Model(&stale).Updates(lockedRow{Title: "c"}).Error
+	if !IsConflict(err) {
+		t.Fatalf("未返回冲突错误：%v", err)
+	}
+	// 提交的版本号优先于模型中的版本号
+	err = db.Model(&row).Updates(map[string]any{"title": "d", "revision": 1}).Error
+	if !IsConflict(err) {
+		t.Fatalf("未校验提交的版本号：%v", err)
+	}
+	if err = Skip(db).Model(&stale).Updates(lockedRow{Title: "e"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var saved lockedRow
+	db.First(&saved, row.Id)
+	if saved.Title != "e" || saved.Revision != 2 {
+		t.Fatalf("跳过校验后数据错误：%+v", saved)
+	}
+}
+
+// 未标记gorm:"version"的version字段不受插件影响
+func TestPlainVersionColumn(t *testing.T) {
+	var db = openDb(t)
+	var row = plainRow{Title: "a"}
+	db.Create(&row)
+	if row.Version != 0 {
+		t.Fatalf("新建时修改了业务字段：%v", row.Version)
+	}
+	if err := db.Model(&row).Updates(map[string]any{"version": 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&row).Updates(map[string]any{"version": 7}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var saved plainRow
+	db.First(&saved, row.Id)
+	if saved.Version != 7 {
+		t.Fatalf("业务字段被改写：%v", saved.Version)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	var db = openDb(t)
+	var check = func(value any) (failure any) {
+		defer func() {
+			failure = recover()
+		}()
+		Require(db, value)
+		return
+	}
+	if failure, ok := check(&lockedRow{Title: "a"}).(*vingo.ParamException); !ok || failure.Field != "revision" {
+		t.Fatalf("未校验缺少的版本号：%v", failure)
+	}
+	if failure := check(&lockedRow{Revision: 1}); failure != nil {
+		t.Fatal(failure)
+	}
+	if failure := check(&plainRow{}); failure != nil {
+		t.Fatal(failure)
+	}
+}
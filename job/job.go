@@ -0,0 +1,282 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：异步任务，任务状态、进度和结果保存在redis中，供接口轮询
+// *****************************************************************************
+
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/db/redis"
+	"github.com/lgdzz/vingo-utils-v2/pool"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+)
+
+type Job struct {
+	Id         string  `json:"id"`
+	Name       string  `json:"name"`
+	Owner      string  `json:"owner"`      // 任务所属者，用于接口权限校验
+	Status     string  `json:"status"`     // pending|running|succeeded|failed|canceled
+	Progress   float64 `json:"progress"`   // 进度百分比，0-100
+	Message    string  `json:"message"`    // 进度说明
+	Result     any     `json:"result"`     // 执行结果
+	Error      string  `json:"error"`      // 失败原因
+	FilePath   string  `json:"filePath"`   // 结果文件路径，接口输出时隐藏
+	FileName   string  `json:"fileName"`   // 结果文件下载名称
+	CreatedAt  int64   `json:"createdAt"`  // 创建时间戳
+	StartedAt  int64   `json:"startedAt"`  // 开始时间戳
+	FinishedAt int64   `json:"finishedAt"` // 结束时间戳
+}
+
+// 是否已结束
+func (s *Job) IsFinished() bool {
+	return s.Status == StatusSucceeded || s.Status == StatusFailed || s.Status == StatusCanceled
+}
+
+type Config struct {
+	RedisApi      *redis.RedisApi
+	Prefix        string                          // 存储key前缀，默认job.
+	Expire        time.Duration                   // 任务信息保存时长，默认24小时
+	MaxWorkers    int                             // 同时执行的最大任务数，默认10
+	Timeout       time.Duration                   // 单个任务最长执行时间，超时后Runner.Context()取消并按失败处理，0为不限制
+	CheckInterval time.Duration                   // 检查取消信号的间隔，默认2秒
+	Check         func(c *vingo.Context, job Job) // 接口访问任务时的权限校验，无权限时panic
+}
+
+type Service struct {
+	Config  Config
+	pool    *pool.GoroutinePool
+	cancels sync.Map
+}
+
+// 创建异步任务服务
+func NewService(config Config) *Service {
+	if config.Prefix == "" {
+		config.Prefix = "job."
+	}
+	if config.Expire == 0 {
+		config.Expire = 24 * time.Hour
+	}
+	if config.MaxWorkers <= 0 {
+		config.MaxWorkers = 10
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = 2 * time.Second
+	}
+	var service = &Service{Config: config}
+	// 常驻协程池，任务结果保存在redis中，不保留在协程池
+	service.pool = pool.NewGoroutinePoolWithOption(context.Background(), config.MaxWorkers, config.MaxWorkers, pool.GoroutinePoolOption{
+		TaskTimeout:    config.Timeout,
+		DiscardResults: true,
+	})
+	service.pool.Run()
+	return service
+}
+
+// 任务执行统计，total为已提交任务数，done为已结束任务数（含失败）
+func (s *Service) Progress() pool.Progress {
+	return s.pool.Progress()
+}
+
+func (s *Service) key(id string) string {
+	return s.Config.Prefix + id
+}
+
+func (s *Service) cancelKey(id string) string {
+	return s.Config.Prefix + id + ".cancel"
+}
+
+func (s *Service) save(job *Job) {
+	s.Config.RedisApi.Set(s.key(job.Id), job, s.Config.Expire)
+}
+
+// 获取任务信息
+func (s *Service) Get(id string) (job Job, exist bool) {
+	exist = s.Config.RedisApi.Get(s.key(id), &job)
+	return
+}
+
+// 获取任务信息，不存在时panic
+func (s *Service) Fetch(id string) Job {
+	job, exist := s.Get(id)
+	if !exist {
+		panic("任务不存在或已过期")
+	}
+	return job
+}
+
+// 启动异步任务，返回任务信息
+// handle返回值作为任务结果，panic时任务失败
+func (s *Service) Start(name string, handle func(runner *Runner) any, owner ...string) Job {
+	var job = Job{
+		Id:        vingo.GetUUID(),
+		Name:      name,
+		Status:    StatusPending,
+		CreatedAt: time.Now().Unix(),
+	}
+	if len(owner) > 0 {
+		job.Owner = owner[0]
+	}
+	s.save(&job)
+
+	// 在协程池中执行，没有空闲worker时排队，不阻塞请求
+	go s.pool.Submit(func(ctx context.Context) pool.Result {
+		return s.run(ctx, job, handle)
+	})
+	return job
+}
+
+// 执行任务，ctx由协程池提供，设置了Timeout时到期取消
+func (s *Service) run(ctx context.Context, job Job, handle func(runner *Runner) any) (res pool.Result) {
+	res = pool.Result{Data: job.Id, Result: "success"}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancels.Store(job.Id, cancel)
+	defer func() {
+		cancel()
+		s.cancels.Delete(job.Id)
+	}()
+
+	// 排队期间已被取消
+	if s.isCanceled(job.Id) {
+		job.Status = StatusCanceled
+		job.FinishedAt = time.Now().Unix()
+		s.save(&job)
+		return
+	}
+
+	job.Status = StatusRunning
+	job.StartedAt = time.Now().Unix()
+	s.save(&job)
+
+	var runner = &Runner{service: s, job: &job, ctx: ctx}
+	go s.watchCancel(ctx, job.Id, cancel)
+
+	defer func() {
+		runner.mu.Lock()
+		defer runner.mu.Unlock()
+		job.FinishedAt = time.Now().Unix()
+		if err := recover(); err != nil {
+			vingo.LogError(fmt.Sprintf("[异步任务]%v执行失败：%v\n%v", job.Name, err, string(debug.Stack())))
+			job.Status = StatusFailed
+			job.Error = fmt.Sprintf("%v", err)
+		} else if errors.Is(ctx.Err(), context.DeadlineExceeded) && job.Status != StatusSucceeded {
+			job.Status = StatusFailed
+			job.Error = "任务执行超时"
+		}
+		if ctx.Err() != nil && s.isCanceled(job.Id) {
+			job.Status = StatusCanceled
+		}
+		if job.Status != StatusSucceeded {
+			res = pool.Result{Data: job.Id, Result: "fail", Error: vingo.Of(fmt.Sprintf("%v %v", job.Status, job.Error))}
+		}
+		s.save(&job)
+	}()
+
+	result := handle(runner)
+	runner.mu.Lock()
+	job.Result = result
+	job.Status = StatusSucceeded
+	job.Progress = 100
+	runner.mu.Unlock()
+	return
+}
+
+// 定时检查取消信号，支持在其他副本上取消任务
+func (s *Service) watchCancel(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(s.Config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.isCanceled(id) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (s *Service) isCanceled(id string) bool {
+	var canceled bool
+	return s.Config.RedisApi.Get(s.cancelKey(id), &canceled) && canceled
+}
+
+// 取消任务，任务需通过Runner.Context()感知取消
+func (s *Service) Cancel(id string) {
+	job := s.Fetch(id)
+	if job.IsFinished() {
+		panic("任务已结束，无法取消")
+	}
+	s.Config.RedisApi.Set(s.cancelKey(id), true, s.Config.Expire)
+	if cancel, ok := s.cancels.Load(id); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+// 任务执行器，供任务内部更新进度
+type Runner struct {
+	service *Service
+	job     *Job
+	ctx     context.Context
+	mu      sync.Mutex
+}
+
+// 任务上下文，任务被取消时Done
+func (s *Runner) Context() context.Context {
+	return s.ctx
+}
+
+// 任务ID
+func (s *Runner) Id() string {
+	return s.job.Id
+}
+
+// 更新进度，percent取值0-100
+func (s *Runner) SetProgress(percent float64, message ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.job.Progress = vingo.ToDecimal(min(max(percent, 0), 100))
+	if len(message) > 0 {
+		s.job.Message = message[0]
+	}
+	s.service.save(s.job)
+}
+
+// 按已完成数量和总数更新进度
+func (s *Runner) SetProgressCount(done int64, total int64, message ...string) {
+	if total <= 0 {
+		return
+	}
+	s.SetProgress(float64(done)/float64(total)*100, message...)
+}
+
+// 设置结果文件，任务成功后可通过下载接口获取
+func (s *Runner) SetFile(filePath string, fileName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.job.FilePath = filePath
+	s.job.FileName = fileName
+}
+
+// 任务已被取消时panic，用于在循环中检查
+func (s *Runner) CheckCanceled() {
+	if s.ctx.Err() != nil {
+		panic("任务已取消")
+	}
+}
@@ -0,0 +1,76 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：异步任务接口
+// *****************************************************************************
+
+package job
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"path/filepath"
+)
+
+type IdQuery struct {
+	Id string `form:"id"`
+}
+
+type IdBody struct {
+	Id string `json:"id"`
+}
+
+// 注册任务状态查询、取消、结果下载路由
+// GET  {group}/job/status?id=
+// POST {group}/job/cancel {"id":""}
+// GET  {group}/job/download?id=
+func (s *Service) RegisterRoutes(g *gin.RouterGroup) {
+	vingo.RoutesGet(g, "/job/status", s.StatusHandler)
+	vingo.RoutesPost(g, "/job/cancel", s.CancelHandler)
+	vingo.RoutesGet(g, "/job/download", s.DownloadHandler)
+}
+
+// 获取任务并校验权限
+func (s *Service) fetchWithCheck(c *vingo.Context, id string) Job {
+	if id == "" {
+		panic("任务ID不能为空")
+	}
+	job := s.Fetch(id)
+	if s.Config.Check != nil {
+		s.Config.Check(c, job)
+	}
+	return job
+}
+
+// 查询任务状态
+func (s *Service) StatusHandler(c *vingo.Context) {
+	query := vingo.GetRequestQuery[IdQuery](c)
+	job := s.fetchWithCheck(c, query.Id)
+	job.FilePath = ""
+	c.ResponseBody(job)
+}
+
+// 取消任务
+func (s *Service) CancelHandler(c *vingo.Context) {
+	body := vingo.GetRequestBody[IdBody](c)
+	s.fetchWithCheck(c, body.Id)
+	s.Cancel(body.Id)
+	c.ResponseSuccess()
+}
+
+// 下载任务结果文件
+func (s *Service) DownloadHandler(c *vingo.Context) {
+	query := vingo.GetRequestQuery[IdQuery](c)
+	job := s.fetchWithCheck(c, query.Id)
+	if job.Status != StatusSucceeded {
+		panic("任务未完成，暂无可下载的结果")
+	}
+	if job.FilePath == "" || !vingo.FileExists(job.FilePath) {
+		panic("任务结果文件不存在")
+	}
+	var fileName = job.FileName
+	if fileName == "" {
+		fileName = filepath.Base(job.FilePath)
+	}
+	c.FileAttachment(job.FilePath, fileName)
+}
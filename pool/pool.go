@@ -0,0 +1,183 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：泛型协程池，结果按提交顺序返回，支持panic恢复、首错取消和有界队列
+// *****************************************************************************
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// 任务已取消或协程池已关闭时提交返回的错误
+var ErrPoolClosed = errors.New("协程池已关闭")
+
+// 任务执行中发生panic
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (s *PanicError) Error() string {
+	return fmt.Sprintf("协程池任务panic：%v", s.Value)
+}
+
+type PoolOption struct {
+	MaxWorkers int  // 最大协程数，默认10
+	QueueSize  int  // 等待队列长度，队列满时Submit阻塞，默认等于MaxWorkers
+	FailFast   bool // 为true时首个错误即取消其余任务（errgroup风格），否则执行全部任务并汇总错误
+}
+
+type Pool[In any, Out any] struct {
+	option  PoolOption
+	handle  func(ctx context.Context, in In) (Out, error)
+	ctx     context.Context
+	cancel  context.CancelFunc
+	queue   chan poolJob[In]
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	sending sync.RWMutex // 保证关闭队列时没有正在投递的任务
+	results []Out
+	errs    []error
+	err     error
+	closed  bool
+}
+
+type poolJob[In any] struct {
+	index int
+	in    In
+}
+
+// 创建并启动协程池
+func NewPool[In any, Out any](ctx context.Context, option PoolOption, handle func(ctx context.Context, in In) (Out, error)) *Pool[In, Out] {
+	if option.MaxWorkers <= 0 {
+		option.MaxWorkers = 10
+	}
+	if option.QueueSize <= 0 {
+		option.QueueSize = option.MaxWorkers
+	}
+	c, cancel := context.WithCancel(ctx)
+	p := &Pool[In, Out]{
+		option: option,
+		handle: handle,
+		ctx:    c,
+		cancel: cancel,
+		queue:  make(chan poolJob[In], option.QueueSize),
+	}
+	for i := 0; i < option.MaxWorkers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+func (p *Pool[In, Out]) worker() {
+	defer p.wg.Done()
+	for job := range p.queue {
+		// 已取消的任务不再执行，仅记录取消原因
+		if err := p.ctx.Err(); err != nil {
+			p.setResult(job.index, *new(Out), err)
+			continue
+		}
+		out, err := p.call(job.in)
+		p.setResult(job.index, out, err)
+	}
+}
+
+// 执行单个任务，panic转换为PanicError
+func (p *Pool[In, Out]) call(in In) (out Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return p.handle(p.ctx, in)
+}
+
+func (p *Pool[In, Out]) setResult(index int, out Out, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results[index] = out
+	p.errs[index] = err
+	if err != nil && p.err == nil && !errors.Is(err, context.Canceled) {
+		p.err = err
+		if p.option.FailFast {
+			p.cancel()
+		}
+	}
+}
+
+// 提交任务，队列已满时阻塞等待，协程池已取消或关闭时返回错误
+func (p *Pool[In, Out]) Submit(in In) error {
+	p.sending.RLock()
+	defer p.sending.RUnlock()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	var job = poolJob[In]{index: len(p.results), in: in}
+	p.results = append(p.results, *new(Out))
+	p.errs = append(p.errs, nil)
+	p.mu.Unlock()
+
+	select {
+	case p.queue <- job:
+		return nil
+	case <-p.ctx.Done():
+		p.setResult(job.index, *new(Out), p.ctx.Err())
+		return p.ctx.Err()
+	}
+}
+
+// 取消所有未执行的任务
+func (p *Pool[In, Out]) Cancel() {
+	p.cancel()
+}
+
+// 关闭协程池并等待所有任务完成
+// 返回按提交顺序排列的结果，FailFast时返回首个错误，否则返回汇总的错误
+func (p *Pool[In, Out]) Wait() ([]Out, error) {
+	p.sending.Lock()
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	p.sending.Unlock()
+	p.wg.Wait()
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.option.FailFast {
+		if p.err == nil {
+			// 外部取消
+			for _, err := range p.errs {
+				if err != nil {
+					return p.results, err
+				}
+			}
+		}
+		return p.results, p.err
+	}
+	return p.results, errors.Join(p.errs...)
+}
+
+// 获取每个任务的错误，与结果下标一一对应，需在Wait之后调用
+func (p *Pool[In, Out]) Errors() []error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]error{}, p.errs...)
+}
@@ -0,0 +1,67 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：基于协程池的切片并发处理
+// *****************************************************************************
+
+package pool
+
+import (
+	"context"
+)
+
+// 并发处理切片元素，最多maxWorkers个协程，结果与输入顺序一致，长度始终等于len(items)
+// 所有元素都会执行，错误汇总后返回；ctx取消后未执行的元素不再执行，结果为零值并返回ctx.Err()
+func Map[T any, R any](ctx context.Context, items []T, maxWorkers int, handle func(ctx context.Context, item T, index int) (R, error)) ([]R, error) {
+	if len(items) == 0 {
+		return make([]R, 0), nil
+	}
+	if maxWorkers <= 0 || maxWorkers > len(items) {
+		maxWorkers = len(items)
+	}
+	p := NewPool[int, R](ctx, PoolOption{MaxWorkers: maxWorkers}, func(ctx context.Context, index int) (R, error) {
+		return handle(ctx, items[index], index)
+	})
+	var submitErr error
+	for index := range items {
+		if submitErr = p.Submit(index); submitErr != nil {
+			break
+		}
+	}
+	results, err := p.Wait()
+	if submitErr != nil {
+		// 未提交的元素补零值，保证下标与items对应
+		results = append(results, make([]R, len(items)-len(results))...)
+		if err == nil {
+			err = submitErr
+		}
+	}
+	return results, err
+}
+
+// 并发遍历切片元素，最多maxWorkers个协程
+// 需要修改元素时请通过items[index]访问
+func ForEach[T any](ctx context.Context, items []T, maxWorkers int, handle func(ctx context.Context, item T, index int) error) error {
+	_, err := Map(ctx, items, maxWorkers, func(ctx context.Context, item T, index int) (struct{}, error) {
+		return struct{}{}, handle(ctx, item, index)
+	})
+	return err
+}
+
+// 将切片按size分组，每组并发处理，最多maxWorkers个协程，结果与分组顺序一致
+func Batch[T any, R any](ctx context.Context, items []T, size int, maxWorkers int, handle func(ctx context.Context, chunk []T, index int) (R, error)) ([]R, error) {
+	return Map(ctx, Chunk(items, size), maxWorkers, handle)
+}
+
+// 将切片按size分组
+func Chunk[T any](items []T, size int) [][]T {
+	if size <= 0 {
+		size = len(items)
+	}
+	var chunks = make([][]T, 0, (len(items)+size-1)/max(size, 1))
+	for start := 0; start < len(items); start += size {
+		end := min(start+size, len(items))
+		chunks = append(chunks, items[start:end:end])
+	}
+	return chunks
+}
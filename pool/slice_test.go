@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	var items = []int{1, 2, 3, 4, 5}
+	results, err := Map(context.Background(), items, 2, func(_ context.Context, item int, _ int) (int, error) {
+		return item * 2, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, item := range items {
+		if results[index] != item*2 {
+			t.Fatalf("结果顺序错误：%v", results)
+		}
+	}
+}
+
+func TestMapCanceled(t *testing.T) {
+	var items = []int{1, 2, 3, 4, 5}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := Map(ctx, items, 2, func(_ context.Context, item int, _ int) (int, error) {
+		return item, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("未返回取消错误：%v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("结果长度%v与元素数量%v不一致", len(results), len(items))
+	}
+}
+
+// 执行过程中取消，结果仍与元素一一对应
+func TestMapCanceledMidway(t *testing.T) {
+	var items = make([]int, 100)
+	for index := range items {
+		items[index] = index + 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, err := Map(ctx, items, 1, func(_ context.Context, item int, index int) (int, error) {
+		if index == 2 {
+			cancel()
+		}
+		return item, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("未返回取消错误：%v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("结果长度%v与元素数量%v不一致", len(results), len(items))
+	}
+	for index, result := range results {
+		if result != 0 && result != items[index] {
+			t.Fatalf("第%v个结果错位：%v", index, result)
+		}
+	}
+	if results[len(results)-1] != 0 {
+		t.Fatal("取消后仍执行了未提交的元素")
+	}
+}
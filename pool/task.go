@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"github.com/lgdzz/vingo-utils-v2/vingo"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // 支持返回结果和错误的任务函数签名
@@ -22,6 +24,32 @@ type Result struct {
 	Error  *string `json:"error"`
 }
 
+// 任务进度
+type Progress struct {
+	Total  int64 `json:"total"`  // 已提交任务数
+	Done   int64 `json:"done"`   // 已完成任务数（含失败）
+	Failed int64 `json:"failed"` // 失败任务数
+}
+
+// 完成百分比，0-100
+func (s Progress) Percent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return vingo.ToDecimal(float64(s.Done) / float64(s.Total) * 100)
+}
+
+type GoroutinePoolOption struct {
+	// 单个任务超时时间，超时后取消任务的ctx并按失败处理，worker继续执行下一个任务，0为不限制
+	// 任务需要监听ctx.Done()及时退出，否则超时的任务仍在后台运行
+	TaskTimeout  time.Duration
+	Retry        int                     // 任务失败（Result.Error不为空）后的重试次数，超时的任务不重试，避免与仍在运行的任务并发执行
+	RetryBackoff time.Duration           // 首次重试等待时长，之后按2倍递增，默认1秒
+	OnProgress   func(progress Progress) // 进度回调，每个任务完成后调用
+	// 不保存任务结果，常驻的协程池（不调用CloseAndWait）需设置，否则结果通道写满后worker阻塞
+	DiscardResults bool
+}
+
 type GoroutinePool struct {
 	maxWorkers int
 	tasks      chan TaskFunc
@@ -29,10 +57,23 @@ type GoroutinePool struct {
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
+	option     GoroutinePoolOption
+	total      atomic.Int64
+	done       atomic.Int64
+	failed     atomic.Int64
+	progress   chan Progress
 }
 
 // 创建协程池，支持 context
 func NewGoroutinePool(ctx context.Context, maxWorkers int, taskNum int) *GoroutinePool {
+	return NewGoroutinePoolWithOption(ctx, maxWorkers, taskNum, GoroutinePoolOption{})
+}
+
+// 创建协程池，支持任务超时、失败重试和进度通知
+func NewGoroutinePoolWithOption(ctx context.Context, maxWorkers int, taskNum int, option GoroutinePoolOption) *GoroutinePool {
+	if option.RetryBackoff <= 0 {
+		option.RetryBackoff = time.Second
+	}
 	c, cancel := context.WithCancel(ctx)
 	return &GoroutinePool{
 		maxWorkers: maxWorkers,
@@ -40,6 +81,8 @@ func NewGoroutinePool(ctx context.Context, maxWorkers int, taskNum int) *Gorouti
 		results:    make(chan Result, taskNum),
 		ctx:        c,
 		cancel:     cancel,
+		option:     option,
+		progress:   make(chan Progress, max(taskNum, 1)),
 	}
 }
 
@@ -55,8 +98,10 @@ func (p *GoroutinePool) Run() {
 					if !ok {
 						return
 					}
-					res := task(p.ctx)
-					p.results <- res
+					var res = p.execute(task)
+					if !p.option.DiscardResults {
+						p.results <- res
+					}
 					p.wg.Done()
 				}
 			}
@@ -64,10 +109,101 @@ func (p *GoroutinePool) Run() {
 	}
 }
 
-// 提交任务
+// 执行任务（含重试），并更新进度
+func (p *GoroutinePool) execute(task TaskFunc) (res Result) {
+	var backoff = p.option.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		var timeout bool
+		res, timeout = p.callTimeout(task)
+		if res.Error == nil || timeout || attempt >= p.option.Retry || p.ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-p.ctx.Done():
+		}
+	}
+
+	var progress = Progress{Total: p.total.Load(), Done: p.done.Add(1)}
+	if res.Error != nil {
+		progress.Failed = p.failed.Add(1)
+	} else {
+		progress.Failed = p.failed.Load()
+	}
+	p.notify(progress)
+	return
+}
+
+// 按任务超时时间执行任务，超时后不再等待任务返回，timeout为true
+func (p *GoroutinePool) callTimeout(task TaskFunc) (Result, bool) {
+	if p.option.TaskTimeout <= 0 {
+		return p.call(p.ctx, task), false
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, p.option.TaskTimeout)
+	defer cancel()
+
+	var ch = make(chan Result, 1)
+	go func() {
+		ch <- p.call(ctx, task)
+	}()
+	select {
+	case res := <-ch:
+		return res, false
+	case <-ctx.Done():
+		return Result{Result: "fail", Error: vingo.Of(fmt.Sprintf("任务执行超时：%v", ctx.Err()))}, true
+	}
+}
+
+// 执行任务，任务panic时转换为失败结果，避免worker退出
+func (p *GoroutinePool) call(ctx context.Context, task TaskFunc) (res Result) {
+	defer func() {
+		if err := recover(); err != nil {
+			vingo.LogError(fmt.Sprintf("协程池中任务执行错误：%v", err))
+			res = Result{Result: "fail", Error: vingo.Of(fmt.Sprintf("%v", err))}
+		}
+	}()
+	return task(ctx)
+}
+
+// 发送进度通知，通道已满时丢弃最旧的进度
+func (p *GoroutinePool) notify(progress Progress) {
+	if p.option.OnProgress != nil {
+		p.option.OnProgress(progress)
+	}
+	for {
+		select {
+		case p.progress <- progress:
+			return
+		default:
+		}
+		select {
+		case <-p.progress:
+		default:
+		}
+	}
+}
+
+// 获取当前进度
+func (p *GoroutinePool) Progress() Progress {
+	return Progress{Total: p.total.Load(), Done: p.done.Load(), Failed: p.failed.Load()}
+}
+
+// 进度通知通道，CloseAndWait后关闭
+func (p *GoroutinePool) ProgressChan() <-chan Progress {
+	return p.progress
+}
+
+// 提交任务，协程池已取消时直接丢弃任务，不再阻塞
 func (p *GoroutinePool) Submit(task TaskFunc) {
 	p.wg.Add(1)
-	p.tasks <- task
+	p.total.Add(1)
+	select {
+	case p.tasks <- task:
+	case <-p.ctx.Done():
+		p.total.Add(-1)
+		p.wg.Done()
+	}
 }
 
 // 取消所有任务
@@ -80,6 +216,7 @@ func (p *GoroutinePool) CloseAndWait() []Result {
 	p.wg.Wait()
 	close(p.tasks)
 	close(p.results)
+	close(p.progress)
 
 	var out []Result
 	for r := range p.results {
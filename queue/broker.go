@@ -0,0 +1,270 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：统一消息队列接口，业务代码依赖接口即可在RedisQueue、NSQ、内存实现间切换
+// *****************************************************************************
+
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nsqio/go-nsq"
+	"sync"
+	"time"
+)
+
+// 消息发布
+type Publisher interface {
+	// 发布消息，value可选类型[[]byte|string|可JSON序列化的值]
+	Publish(topic string, value any) error
+	// 发布延迟消息
+	PublishDelay(topic string, value any, delay time.Duration) error
+}
+
+// 消息订阅
+type Subscriber interface {
+	// 订阅主题，同一channel的多个订阅者竞争消费，不同channel各自收到一份消息
+	// handle返回错误或panic时消息将重新投递，具体策略由实现决定
+	Subscribe(topic string, channel string, handle func(message []byte) error) error
+}
+
+type Broker interface {
+	Publisher
+	Subscriber
+}
+
+// 将消息内容编码为字节
+func encodeMessage(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// 将panic转换为错误
+func catchError(handle func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch v := r.(type) {
+			case error:
+				err = v
+			default:
+				err = fmt.Errorf("%v", v)
+			}
+		}
+	}()
+	handle()
+	return
+}
+
+// 订阅并将消息解析为T
+func SubscribeJson[T any](subscriber Subscriber, topic string, channel string, handle func(body T) error) error {
+	return subscriber.Subscribe(topic, channel, func(message []byte) error {
+		var body T
+		if err := json.Unmarshal(message, &body); err != nil {
+			return err
+		}
+		return handle(body)
+	})
+}
+
+// ---------------------------------------------------------------------------
+// RedisQueue实现
+
+type RedisBroker struct {
+	Queue *RedisQueue
+}
+
+// 创建基于RedisQueue的Broker，queue为空时使用queue.Redis
+func NewRedisBroker(queue *RedisQueue) *RedisBroker {
+	if queue == nil {
+		queue = &Redis
+	}
+	return &RedisBroker{Queue: queue}
+}
+
+func (s *RedisBroker) Publish(topic string, value any) error {
+	message, err := encodeMessage(value)
+	if err != nil {
+		return err
+	}
+	return catchError(func() {
+		s.Queue.Push(topic, string(message))
+	})
+}
+
+func (s *RedisBroker) PublishDelay(topic string, value any, delay time.Duration) error {
+	message, err := encodeMessage(value)
+	if err != nil {
+		return err
+	}
+	return catchError(func() {
+		s.Queue.PushDelay(topic, string(message), int64(delay/time.Second))
+	})
+}
+
+// redis列表本身即竞争消费，channel参数不生效
+// 消费失败时沿用RedisQueue的重试机制，在RetryWaitTime后重新投递
+func (s *RedisBroker) Subscribe(topic string, channel string, handle func(message []byte) error) error {
+	s.Queue.StartMonitorHandler(topic, &brokerHandle{handle: handle}, nil)
+	return nil
+}
+
+type brokerHandle struct {
+	handle func(message []byte) error
+}
+
+func (s *brokerHandle) HandleMessage(message *string, methods any) {
+	if err := s.handle([]byte(*message)); err != nil {
+		panic(err.Error())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// NSQ实现
+
+type NsqBroker struct {
+	Service *NsqService
+}
+
+// 创建基于NsqService的Broker，service为空时使用queue.Nsq
+func NewNsqBroker(service *NsqService) *NsqBroker {
+	if service == nil {
+		service = &Nsq
+	}
+	return &NsqBroker{Service: service}
+}
+
+func (s *NsqBroker) Publish(topic string, value any) error {
+	message, err := encodeMessage(value)
+	if err != nil {
+		return err
+	}
+	return catchError(func() {
+		s.Service.ProduceMessage(topic, message)
+	})
+}
+
+func (s *NsqBroker) PublishDelay(topic string, value any, delay time.Duration) error {
+	message, err := encodeMessage(value)
+	if err != nil {
+		return err
+	}
+	return catchError(func() {
+		s.Service.DeferredPublish(topic, delay, message)
+	})
+}
+
+// 消费失败时由nsq按配置重新投递
+func (s *NsqBroker) Subscribe(topic string, channel string, handle func(message []byte) error) error {
+	s.Service.ConsumeMessagesAsync(topic, channel, nsq.HandlerFunc(func(message *nsq.Message) error {
+		return catchError(func() {
+			if err := handle(message.Body); err != nil {
+				panic(err)
+			}
+		})
+	}))
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// 内存实现，用于单元测试
+
+type MemoryBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[string][]func(message []byte) error
+	cursor      map[string]int
+	messages    map[string][][]byte
+	errs        []error
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		subscribers: map[string]map[string][]func(message []byte) error{},
+		cursor:      map[string]int{},
+		messages:    map[string][][]byte{},
+	}
+}
+
+// 同步投递给每个channel中的一个订阅者（轮询），处理错误记录到Errors中
+func (s *MemoryBroker) Publish(topic string, value any) error {
+	message, err := encodeMessage(value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.messages[topic] = append(s.messages[topic], message)
+	var handles = make([]func(message []byte) error, 0)
+	for channel, items := range s.subscribers[topic] {
+		key := topic + "/" + channel
+		handles = append(handles, items[s.cursor[key]%len(items)])
+		s.cursor[key]++
+	}
+	s.mu.Unlock()
+
+	for _, handle := range handles {
+		err = catchError(func() {
+			if e := handle(message); e != nil {
+				panic(e)
+			}
+		})
+		if err != nil {
+			s.mu.Lock()
+			s.errs = append(s.errs, err)
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+func (s *MemoryBroker) PublishDelay(topic string, value any, delay time.Duration) error {
+	if _, err := encodeMessage(value); err != nil {
+		return err
+	}
+	time.AfterFunc(delay, func() {
+		_ = s.Publish(topic, value)
+	})
+	return nil
+}
+
+func (s *MemoryBroker) Subscribe(topic string, channel string, handle func(message []byte) error) error {
+	if handle == nil {
+		return errors.New("handle不能为空")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers[topic] == nil {
+		s.subscribers[topic] = map[string][]func(message []byte) error{}
+	}
+	s.subscribers[topic][channel] = append(s.subscribers[topic][channel], handle)
+	return nil
+}
+
+// 获取指定主题已发布的消息
+func (s *MemoryBroker) Messages(topic string) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte{}, s.messages[topic]...)
+}
+
+// 获取订阅者处理失败的错误
+func (s *MemoryBroker) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error{}, s.errs...)
+}
+
+// 清空已记录的消息和错误，订阅者保留
+func (s *MemoryBroker) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = map[string][][]byte{}
+	s.errs = nil
+}
@@ -0,0 +1,171 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：cron表达式解析
+// *****************************************************************************
+
+package queue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 预定义表达式
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronBounds struct {
+	min int
+	max int
+}
+
+var (
+	cronMinute = cronBounds{0, 59}
+	cronHour   = cronBounds{0, 23}
+	cronDom    = cronBounds{1, 31}
+	cronMonth  = cronBounds{1, 12}
+	cronDow    = cronBounds{0, 7} // 0和7均表示周日
+)
+
+// cron表达式，格式：分 时 日 月 周
+// 支持 * , - / 以及@daily、@hourly等预定义表达式
+type CronExpr struct {
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+// 解析cron表达式
+func ParseCron(spec string) (*CronExpr, error) {
+	spec = strings.TrimSpace(spec)
+	if value, ok := cronDescriptors[spec]; ok {
+		spec = value
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron表达式[%v]格式错误，需要5个字段：分 时 日 月 周", spec)
+	}
+
+	var expr CronExpr
+	var err error
+	if expr.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, err
+	}
+	if expr.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, err
+	}
+	if expr.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, err
+	}
+	if expr.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, err
+	}
+	if expr.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, err
+	}
+	// 周日统一为0
+	if expr.dow&(1<<7) > 0 {
+		expr.dow |= 1
+	}
+	expr.domStar = fields[2] == "*" || fields[2] == "?"
+	expr.dowStar = fields[4] == "*" || fields[4] == "?"
+	return &expr, nil
+}
+
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		var start, end, step = bounds.min, bounds.max, 1
+
+		rangeText := part
+		if index := strings.Index(part, "/"); index >= 0 {
+			value, err := strconv.Atoi(part[index+1:])
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("cron字段[%v]步长错误", field)
+			}
+			step = value
+			rangeText = part[:index]
+		}
+
+		switch {
+		case rangeText == "*" || rangeText == "?":
+		case strings.Contains(rangeText, "-"):
+			arr := strings.SplitN(rangeText, "-", 2)
+			var err1, err2 error
+			start, err1 = strconv.Atoi(arr[0])
+			end, err2 = strconv.Atoi(arr[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("cron字段[%v]范围错误", field)
+			}
+		default:
+			value, err := strconv.Atoi(rangeText)
+			if err != nil {
+				return 0, fmt.Errorf("cron字段[%v]格式错误", field)
+			}
+			start = value
+			// 形如 5/10 表示从5开始每10个单位
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("cron字段[%v]超出范围%d-%d", field, bounds.min, bounds.max)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func (s *CronExpr) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) > 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) > 0
+	// 与标准cron一致：日和周同时被限定时满足其一即可
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// 计算指定时间之后的下一次执行时间，找不到时返回零值
+func (s *CronExpr) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// 最多向后查找5年
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
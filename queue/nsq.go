@@ -5,12 +5,25 @@ import (
 	"github.com/lgdzz/vingo-utils-v2/vingo"
 	"github.com/nsqio/go-nsq"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+type NsqConfig struct {
+	Addrs        []string    // nsqd地址，生产者在多个地址间轮询发布并故障转移，默认127.0.0.1:4150
+	LookupdAddrs []string    // nsqlookupd地址，设置后消费者通过服务发现连接nsqd，否则直连Addrs
+	Config       *nsq.Config // nsq配置，为空时使用默认配置
+}
+
 type NsqService struct {
-	producer     *nsq.Producer
+	producers    []*nsq.Producer
 	producerOnce sync.Once
-	Addr         string
+	consumers    []*nsq.Consumer
+	mu           sync.Mutex
+	next         uint32
+	Addr         string   // nsqd地址，兼容单地址用法，等同于Addrs[0]
+	Addrs        []string // nsqd地址
+	LookupdAddrs []string // nsqlookupd地址
 	Config       *nsq.Config
 }
 
@@ -18,29 +31,64 @@ var Nsq NsqService
 
 // 初始化服务（只需要执行1次）
 func NsqInit(addr *string, config *nsq.Config) {
-	if addr == nil {
-		Nsq.Addr = "127.0.0.1:4150"
+	var option = NsqConfig{Config: config}
+	if addr != nil {
+		option.Addrs = []string{*addr}
 	}
-	if config == nil {
+	InitNsq(option)
+}
+
+// 初始化服务，支持多个nsqd和nsqlookupd（只需要执行1次）
+func InitNsq(config NsqConfig) {
+	if len(config.Addrs) > 0 {
+		Nsq.Addrs = config.Addrs
+	} else {
+		Nsq.Addrs = []string{"127.0.0.1:4150"}
+	}
+	Nsq.Addr = Nsq.Addrs[0]
+	Nsq.LookupdAddrs = config.LookupdAddrs
+
+	if config.Config != nil {
+		Nsq.Config = config.Config
+	} else {
 		Nsq.Config = nsq.NewConfig()
 		Nsq.Config.MaxInFlight = 1 // 设置每个消费者的最大并发处理消息数为1
-		Nsq.Config.MaxAttempts = 0 // 指定重连的最大尝试次数。默认为 0，表示无限次尝试重连。
+		Nsq.Config.MaxAttempts = 0 // 消息最大投递次数，默认为 0，表示无限次重新投递。
 	}
 	Nsq.initProducer()
 }
 
 func (s *NsqService) initProducer() {
-	var err error
 	s.producerOnce.Do(func() {
-		s.producer, err = nsq.NewProducer(s.Addr, s.Config)
-		if err != nil {
-			vingo.LogInfo(fmt.Sprintf("[NSQ]创建生产者失败：%v", err.Error()))
-		} else {
-			vingo.LogInfo("[NSQ]创建生产者成功.")
+		for _, addr := range s.Addrs {
+			producer, err := nsq.NewProducer(addr, s.Config)
+			if err != nil {
+				vingo.LogInfo(fmt.Sprintf("[NSQ]创建生产者失败：%v，%v", addr, err.Error()))
+				continue
+			}
+			s.producers = append(s.producers, producer)
+			vingo.LogInfo(fmt.Sprintf("[NSQ]创建生产者成功：%v", addr))
 		}
 	})
 }
 
+// 从下一个生产者开始依次尝试发布，全部失败时抛出最后一个错误
+func (s *NsqService) publish(handle func(producer *nsq.Producer) error) {
+	var total = len(s.producers)
+	if total == 0 {
+		panic("[NSQ]没有可用的生产者")
+	}
+	var start = int(atomic.AddUint32(&s.next, 1)-1) % total
+	var err error
+	for i := 0; i < total; i++ {
+		if err = handle(s.producers[(start+i)%total]); err == nil {
+			return
+		}
+		vingo.LogError(fmt.Sprintf("[NSQ]发布消息失败，尝试下一个生产者：%v", err.Error()))
+	}
+	panic(err.Error())
+}
+
 // ProduceMessageAsync 生产消息（异步）
 func (s *NsqService) ProduceMessageAsync(topic string, message []byte) {
 	go s.ProduceMessage(topic, message)
@@ -48,10 +96,16 @@ func (s *NsqService) ProduceMessageAsync(topic string, message []byte) {
 
 // ProduceMessage 生产消息
 func (s *NsqService) ProduceMessage(topic string, message []byte) {
-	err := s.producer.Publish(topic, message)
-	if err != nil {
-		panic(err.Error())
-	}
+	s.publish(func(producer *nsq.Producer) error {
+		return producer.Publish(topic, message)
+	})
+}
+
+// DeferredPublish 生产延迟消息，delay后才会投递给消费者
+func (s *NsqService) DeferredPublish(topic string, delay time.Duration, message []byte) {
+	s.publish(func(producer *nsq.Producer) error {
+		return producer.DeferredPublish(topic, delay, message)
+	})
 }
 
 // ConsumeMessagesAsync 消费消息（异步）
@@ -59,21 +113,34 @@ func (s *NsqService) ConsumeMessagesAsync(topic string, channel string, handler
 	go s.ConsumeMessages(topic, channel, handler)
 }
 
-// ConsumeMessages 消费消息
+// ConsumeMessages 消费消息，阻塞直到Stop被调用
 func (s *NsqService) ConsumeMessages(topic string, channel string, handler nsq.Handler) {
+	s.consume(topic, channel, handler, s.Config)
+}
+
+// 使用指定配置创建消费者并阻塞消费
+func (s *NsqService) consume(topic string, channel string, handler nsq.Handler, config *nsq.Config) {
 	// 创建消费者
-	consumer, err := nsq.NewConsumer(topic, channel, s.Config)
+	consumer, err := nsq.NewConsumer(topic, channel, config)
 	if err != nil {
 		vingo.LogInfo(fmt.Sprintf("[NSQ]创建消费者失败：%v", err.Error()))
-	} else {
-		vingo.LogInfo("[NSQ]创建消费者成功.")
+		return
 	}
+	vingo.LogInfo("[NSQ]创建消费者成功.")
 
 	// 设置消息处理程序
 	consumer.AddHandler(handler)
 
-	// 连接到NSQ服务器
-	err = consumer.ConnectToNSQD(s.Addr)
+	s.mu.Lock()
+	s.consumers = append(s.consumers, consumer)
+	s.mu.Unlock()
+
+	// 优先通过nsqlookupd发现nsqd，否则直连nsqd
+	if len(s.LookupdAddrs) > 0 {
+		err = consumer.ConnectToNSQLookupds(s.LookupdAddrs)
+	} else {
+		err = consumer.ConnectToNSQDs(s.Addrs)
+	}
 	if err != nil {
 		vingo.LogInfo(fmt.Sprintf("[NSQ]消费者连接NSQ服务失败：%v", err.Error()))
 	}
@@ -81,3 +148,24 @@ func (s *NsqService) ConsumeMessages(topic string, channel string, handler nsq.H
 	// 阻塞等待直到接收到退出信号
 	<-consumer.StopChan
 }
+
+// Stop 停止服务，等待消费者处理完正在消费的消息，再停止生产者
+func (s *NsqService) Stop() {
+	s.mu.Lock()
+	consumers := s.consumers
+	s.consumers = nil
+	s.mu.Unlock()
+
+	for _, consumer := range consumers {
+		consumer.Stop()
+	}
+	for _, consumer := range consumers {
+		<-consumer.StopChan
+	}
+	vingo.LogInfo("[NSQ]消费者已停止.")
+
+	for _, producer := range s.producers {
+		producer.Stop()
+	}
+	vingo.LogInfo("[NSQ]生产者已停止.")
+}
@@ -0,0 +1,158 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：NSQ消息JSON解析适配器，支持失败退避重试和死信投递
+// *****************************************************************************
+
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"github.com/nsqio/go-nsq"
+	"time"
+)
+
+// 消费失败重试策略
+type NsqRetryPolicy struct {
+	MaxAttempts     uint16        // 最大尝试次数，超过后投递到死信主题，默认5次
+	BackoffBase     time.Duration // 首次重试等待时长，之后按2倍递增，默认5秒
+	BackoffMax      time.Duration // 最大重试等待时长，默认10分钟
+	DeadLetterTopic string        // 死信主题，默认为{topic}.dead
+}
+
+// 死信消息
+type NsqDeadLetter struct {
+	Topic    string `json:"topic"`    // 原主题
+	Channel  string `json:"channel"`  // 原通道
+	Body     string `json:"body"`     // 原消息内容
+	Error    string `json:"error"`    // 最后一次失败原因
+	Attempts uint16 `json:"attempts"` // 已尝试次数
+	FailedAt int64  `json:"failedAt"` // 失败时间戳
+}
+
+// 将消息内容解析为T后交给Handle处理
+type NsqJsonHandler[T any] struct {
+	Service *NsqService // 投递死信使用的服务，默认为queue.Nsq
+	Topic   string
+	Channel string
+	Policy  NsqRetryPolicy
+	Handle  func(body T) error
+}
+
+// 创建JSON消息处理器
+func NewNsqJsonHandler[T any](topic string, channel string, handle func(body T) error, policy ...NsqRetryPolicy) *NsqJsonHandler[T] {
+	var handler = NsqJsonHandler[T]{
+		Service: &Nsq,
+		Topic:   topic,
+		Channel: channel,
+		Handle:  handle,
+	}
+	if len(policy) > 0 {
+		handler.Policy = policy[0]
+	}
+	if handler.Policy.MaxAttempts == 0 {
+		handler.Policy.MaxAttempts = 5
+	}
+	if handler.Policy.BackoffBase == 0 {
+		handler.Policy.BackoffBase = 5 * time.Second
+	}
+	if handler.Policy.BackoffMax == 0 {
+		handler.Policy.BackoffMax = 10 * time.Minute
+	}
+	if handler.Policy.DeadLetterTopic == "" {
+		handler.Policy.DeadLetterTopic = topic + ".dead"
+	}
+	return &handler
+}
+
+// 计算第attempts次失败后的重试等待时长
+func (s *NsqRetryPolicy) Backoff(attempts uint16) time.Duration {
+	var delay = s.BackoffBase
+	for i := uint16(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= s.BackoffMax {
+			return s.BackoffMax
+		}
+	}
+	return delay
+}
+
+func (s *NsqJsonHandler[T]) HandleMessage(message *nsq.Message) error {
+	message.DisableAutoResponse()
+
+	var body T
+	if err := json.Unmarshal(message.Body, &body); err != nil {
+		// 消息格式错误重试也无法成功，直接投递到死信主题
+		s.deadLetter(message, fmt.Sprintf("消息解析失败：%v", err.Error()))
+		return nil
+	}
+
+	if err := s.call(body); err != nil {
+		vingo.LogError(fmt.Sprintf("[NSQ]消费失败，Topic：%v，Message：%v，Attempts：%d，Error：%v", s.Topic, string(message.Body), message.Attempts, err.Error()))
+		if message.Attempts >= s.Policy.MaxAttempts {
+			s.deadLetter(message, err.Error())
+		} else {
+			// 等待时长由Policy计算，不触发消费者整体退避
+			message.RequeueWithoutBackoff(s.Policy.Backoff(message.Attempts))
+		}
+		return nil
+	}
+	message.Finish()
+	return nil
+}
+
+// 消费者配置的MaxAttempts不为0且投递次数超过时，nsq不再调用HandleMessage而是直接Finish，
+// 此时在Finish前投递到死信主题，避免消息丢失
+func (s *NsqJsonHandler[T]) LogFailedMessage(message *nsq.Message) {
+	s.deadLetter(message, fmt.Sprintf("超过消费者最大投递次数：%d", message.Attempts-1))
+}
+
+// 执行业务处理，panic转换为错误
+func (s *NsqJsonHandler[T]) call(body T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return s.Handle(body)
+}
+
+// 投递到死信主题，投递失败时重新入队等待下次处理
+func (s *NsqJsonHandler[T]) deadLetter(message *nsq.Message, reason string) {
+	defer func() {
+		if r := recover(); r != nil {
+			vingo.LogError(fmt.Sprintf("[NSQ]死信投递失败，Topic：%v，Error：%v", s.Policy.DeadLetterTopic, r))
+			message.RequeueWithoutBackoff(s.Policy.BackoffMax)
+		}
+	}()
+	s.Service.ProduceJson(s.Policy.DeadLetterTopic, NsqDeadLetter{
+		Topic:    s.Topic,
+		Channel:  s.Channel,
+		Body:     string(message.Body),
+		Error:    reason,
+		Attempts: message.Attempts,
+		FailedAt: time.Now().Unix(),
+	})
+	message.Finish()
+}
+
+// ProduceJson 生产消息，value序列化为JSON后发布
+func (s *NsqService) ProduceJson(topic string, value any) {
+	message, err := json.Marshal(value)
+	if err != nil {
+		panic(err.Error())
+	}
+	s.ProduceMessage(topic, message)
+}
+
+// NsqConsumeJson 消费JSON消息（异步），消息解析为T后交给handle处理
+func NsqConsumeJson[T any](service *NsqService, topic string, channel string, handle func(body T) error, policy ...NsqRetryPolicy) {
+	handler := NewNsqJsonHandler[T](topic, channel, handle, policy...)
+	handler.Service = service
+	// 重试次数由Policy控制，nsq的MaxAttempts不为0时会在死信投递前自动Finish消息
+	var config = *service.Config
+	config.MaxAttempts = 0
+	go service.consume(topic, channel, handler, &config)
+}
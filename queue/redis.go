@@ -77,6 +77,15 @@ func (s *RedisQueue) toString(value any) string {
 	}
 }
 
+// 输出日志，调试模式在控制台输出，否则记录到日志文件
+func (s *RedisQueue) log(message string) {
+	if *s.Config.Debug {
+		fmt.Println(message)
+	} else {
+		vingo.LogError(message)
+	}
+}
+
 func (s *RedisQueue) getTopic(topic string) string {
 	return fmt.Sprintf("%v%v.queue", s.Config.RedisApi.Config.Prefix, topic)
 }
@@ -112,7 +121,12 @@ func (s *RedisQueue) PushDelay(topic string, value any, delayed int64) bool {
 
 // 开始监听队列信息
 func (s *RedisQueue) StartMonitor(topic string, methods any) {
-	go s.monitorGuard(topic, s.Config.Handle, methods)
+	s.StartMonitorHandler(topic, s.Config.Handle, methods)
+}
+
+// 开始监听队列信息，使用指定的消费处理方法调度中心
+func (s *RedisQueue) StartMonitorHandler(topic string, handler Handler, methods any) {
+	go s.monitorGuard(topic, handler, methods)
 	go s.monitorGuardDelay(topic)
 }
 
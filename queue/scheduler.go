@@ -0,0 +1,325 @@
+// *****************************************************************************
+// 作者: lgdz
+// 创建时间: 2026/10/19
+// 描述：定时任务调度，基于RedisQueue延迟队列投递，多副本部署时只有主节点负责调度
+// *****************************************************************************
+
+package queue
+
+import (
+	"fmt"
+	"github.com/duke-git/lancet/v2/pointer"
+	"github.com/go-redis/redis"
+	"github.com/lgdzz/vingo-utils-v2/vingo"
+	"time"
+)
+
+const (
+	ScheduleStatusSuccess = "success"
+	ScheduleStatusFail    = "fail"
+)
+
+// 租约续期脚本，只有当前持有者才能续期
+const leaderRenewScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+
+// 租约释放脚本，只有当前持有者才能释放
+const leaderReleaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+type SchedulerConfig struct {
+	Queue        *RedisQueue // 投递使用的队列，默认为queue.Redis
+	Name         string      // 调度器名称，用于区分redis存储，默认vingo
+	Topic        string      // 到期任务投递的队列主题，默认scheduler
+	TickInterval *int        // 调度检查间隔，单位：秒，默认1秒
+	LeaderTTL    *int        // 主节点租约有效期，单位：秒，默认10秒
+}
+
+// 定时任务定义
+type ScheduleJob struct {
+	Name   string `json:"name"`   // 任务唯一名称
+	Cron   string `json:"cron"`   // cron表达式：分 时 日 月 周，与Every二选一
+	Every  int64  `json:"every"`  // 固定间隔，单位：秒
+	Method string `json:"method"` // 消费方法名
+	Params []any  `json:"params"` // 消费方法参数
+}
+
+// 定时任务状态
+type ScheduleState struct {
+	ScheduleJob
+	NextRunAt  int64  `json:"nextRunAt"`  // 下次执行时间戳
+	LastRunAt  int64  `json:"lastRunAt"`  // 最近执行时间戳
+	LastStatus string `json:"lastStatus"` // 最近执行状态：success|fail
+	LastError  string `json:"lastError"`  // 最近执行错误信息
+}
+
+type scheduleStatus struct {
+	LastRunAt  int64  `json:"lastRunAt"`
+	LastStatus string `json:"lastStatus"`
+	LastError  string `json:"lastError"`
+}
+
+// 投递到队列中的消息，兼容MessagePackage格式
+type ScheduleMessage struct {
+	Schedule string
+	RunAt    int64
+	Method   string
+	Params   []any
+}
+
+type Scheduler struct {
+	Config SchedulerConfig
+	nodeId string
+	stop   chan struct{}
+}
+
+// 创建调度器
+func NewScheduler(config SchedulerConfig) *Scheduler {
+	if config.Queue == nil {
+		config.Queue = &Redis
+	}
+	if config.Name == "" {
+		config.Name = "vingo"
+	}
+	if config.Topic == "" {
+		config.Topic = "scheduler"
+	}
+	if config.TickInterval == nil {
+		config.TickInterval = pointer.Of(1)
+	}
+	if config.LeaderTTL == nil {
+		config.LeaderTTL = pointer.Of(10)
+	}
+	return &Scheduler{
+		Config: config,
+		nodeId: vingo.GetUUID(),
+	}
+}
+
+func (s *Scheduler) client() *redis.Client {
+	return s.Config.Queue.Config.RedisApi.Client
+}
+
+func (s *Scheduler) getKey(name string) string {
+	return fmt.Sprintf("%v%v.scheduler.%v", s.Config.Queue.Config.RedisApi.Config.Prefix, s.Config.Name, name)
+}
+
+// 计算下一次执行时间
+func (s *ScheduleJob) NextTime(after time.Time) time.Time {
+	if s.Every > 0 {
+		return after.Add(time.Duration(s.Every) * time.Second)
+	}
+	expr, err := ParseCron(s.Cron)
+	if err != nil {
+		panic(err.Error())
+	}
+	return expr.Next(after)
+}
+
+func (s *ScheduleJob) check() {
+	if s.Name == "" {
+		panic("定时任务名称不能为空")
+	}
+	if s.Method == "" {
+		panic(fmt.Sprintf("定时任务[%v]消费方法不能为空", s.Name))
+	}
+	if s.Every <= 0 {
+		if _, err := ParseCron(s.Cron); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// 添加或更新定时任务，执行规则未变化时保留原有的下次执行时间
+func (s *Scheduler) Add(job ScheduleJob) {
+	job.check()
+	var state ScheduleState
+	if s.fetchState(job.Name, &state) && state.Cron == job.Cron && state.Every == job.Every {
+		state.ScheduleJob = job
+	} else {
+		state = ScheduleState{ScheduleJob: job, NextRunAt: job.NextTime(time.Now()).Unix()}
+	}
+	s.saveState(state)
+}
+
+// 删除定时任务
+func (s *Scheduler) Remove(name string) {
+	s.client().HDel(s.getKey("jobs"), name)
+	s.client().HDel(s.getKey("status"), name)
+}
+
+// 获取所有定时任务及执行状态
+func (s *Scheduler) List() []ScheduleState {
+	jobs, err := s.client().HGetAll(s.getKey("jobs")).Result()
+	if err != nil {
+		panic(err.Error())
+	}
+	statuses, err := s.client().HGetAll(s.getKey("status")).Result()
+	if err != nil {
+		panic(err.Error())
+	}
+	var result = make([]ScheduleState, 0, len(jobs))
+	for name, text := range jobs {
+		var state ScheduleState
+		vingo.StringToJson(text, &state)
+		if value, ok := statuses[name]; ok {
+			var status scheduleStatus
+			vingo.StringToJson(value, &status)
+			state.LastRunAt = status.LastRunAt
+			state.LastStatus = status.LastStatus
+			state.LastError = status.LastError
+		}
+		result = append(result, state)
+	}
+	return result
+}
+
+func (s *Scheduler) fetchState(name string, state *ScheduleState) bool {
+	text, err := s.client().HGet(s.getKey("jobs"), name).Result()
+	if err == redis.Nil {
+		return false
+	} else if err != nil {
+		panic(err.Error())
+	}
+	vingo.StringToJson(text, state)
+	return true
+}
+
+func (s *Scheduler) saveState(state ScheduleState) {
+	if err := s.client().HSet(s.getKey("jobs"), state.Name, vingo.JsonToString(state)).Err(); err != nil {
+		panic(err.Error())
+	}
+}
+
+// 记录执行结果
+func (s *Scheduler) report(name string, err any) {
+	var status = scheduleStatus{LastRunAt: time.Now().Unix(), LastStatus: ScheduleStatusSuccess}
+	if err != nil {
+		status.LastStatus = ScheduleStatusFail
+		status.LastError = fmt.Sprintf("%v", err)
+	}
+	s.client().HSet(s.getKey("status"), name, vingo.JsonToString(status))
+}
+
+// 竞选主节点，已是主节点时续期
+func (s *Scheduler) elect() bool {
+	var key = s.getKey("leader")
+	var ttl = time.Duration(*s.Config.LeaderTTL) * time.Second
+	ok, err := s.client().SetNX(key, s.nodeId, ttl).Result()
+	if err != nil {
+		panic(err.Error())
+	}
+	if ok {
+		return true
+	}
+	r, err := s.client().Eval(leaderRenewScript, []string{key}, s.nodeId, ttl.Milliseconds()).Int64()
+	if err != nil {
+		panic(err.Error())
+	}
+	return r > 0
+}
+
+// 是否为主节点
+func (s *Scheduler) IsLeader() bool {
+	id, err := s.client().Get(s.getKey("leader")).Result()
+	return err == nil && id == s.nodeId
+}
+
+// 将到期的任务投递到延迟队列
+func (s *Scheduler) dispatch() {
+	if !s.elect() {
+		return
+	}
+	jobs, err := s.client().HGetAll(s.getKey("jobs")).Result()
+	if err != nil {
+		panic(err.Error())
+	}
+	var now = time.Now()
+	// 提前一个检查间隔投递，由延迟队列负责准时触发
+	var horizon = now.Unix() + int64(*s.Config.TickInterval)
+	for _, text := range jobs {
+		var state ScheduleState
+		vingo.StringToJson(text, &state)
+		if state.NextRunAt == 0 || state.NextRunAt > horizon {
+			continue
+		}
+		var delay = state.NextRunAt - now.Unix()
+		if delay < 0 {
+			delay = 0
+		}
+		s.Config.Queue.PushDelay(s.Config.Topic, ScheduleMessage{
+			Schedule: state.Name,
+			RunAt:    state.NextRunAt,
+			Method:   state.Method,
+			Params:   state.Params,
+		}, delay)
+
+		// 错过的执行周期不再补偿，从当前时间重新计算
+		var base = time.Unix(state.NextRunAt, 0)
+		if base.Before(now) {
+			base = now
+		}
+		var next = state.NextTime(base)
+		if next.IsZero() {
+			state.NextRunAt = 0
+		} else {
+			state.NextRunAt = next.Unix()
+		}
+		s.saveState(state)
+	}
+}
+
+// 开始调度并监听调度队列
+// methods-消费方法所在的结构体，与RedisQueue.StartMonitor一致
+func (s *Scheduler) Start(methods any) {
+	s.stop = make(chan struct{})
+	s.Config.Queue.StartMonitorHandler(s.Config.Topic, &scheduleHandle{scheduler: s, handler: s.Config.Queue.Config.Handle}, methods)
+	go s.runGuard(s.stop)
+}
+
+// 停止调度，并释放主节点租约
+func (s *Scheduler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.client().Eval(leaderReleaseScript, []string{s.getKey("leader")}, s.nodeId)
+}
+
+// 调度守卫
+func (s *Scheduler) runGuard(stop chan struct{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			time.Sleep(time.Second * time.Duration(*s.Config.Queue.Config.AutoBootTime))
+			s.Config.Queue.log(fmt.Sprintf("[定时任务]调度器异常，进行重启：%v", err))
+			s.runGuard(stop)
+		}
+	}()
+	ticker := time.NewTicker(time.Second * time.Duration(*s.Config.TickInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.dispatch()
+		}
+	}
+}
+
+// 调度消息消费处理，记录执行状态后交由原处理方法调度中心处理
+type scheduleHandle struct {
+	scheduler *Scheduler
+	handler   Handler
+}
+
+func (s *scheduleHandle) HandleMessage(message *string, methods any) {
+	var body ScheduleMessage
+	vingo.StringToJson(*message, &body)
+	defer func() {
+		err := recover()
+		s.scheduler.report(body.Schedule, err)
+		if err != nil {
+			panic(err)
+		}
+	}()
+	s.handler.HandleMessage(message, methods)
+}
@@ -1,27 +1,125 @@
 package vingo
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// 数据比对，支持嵌套结构体、指针、切片、map的深度比对
+//
+// 字段标签：
+//
+//	diff:"名称"        比对结果中显示的字段名称，未设置时取gorm标签中的comment
+//	diff:"-"           不参与比对
+//	diff:"名称,money"  使用指定格式化方法输出值，内置money（保留两位小数）、cent（分转元）、date、datetime
+//	diff:",whole"      切片、map、结构体整体比对，不展开子项
+//
+// 与旧版本的差异：
+//   - 基础类型字段的键仍为字段名，结果与旧版本一致
+//   - 嵌套结构体、切片、map字段不再以字段名整体记录一项，而是展开为字段路径（如Profile.Name），
+//     IsChange("Profile")仍可判断任一子项变更；需要旧的整体比对时在字段上使用diff:",whole"
+//   - IsChangeAnd在全部字段都被修改时返回true（旧版本始终返回false）
 type DiffBox struct {
 	Old    any
 	New    any
-	Result *map[string]DiffItem
+	Result *map[string]DiffItem // 以字段路径为键，如：Title、Profile.Name、Items.0.Price
+
+	keys []string // 比对结果顺序
 }
 
+// 变更类型，与JSON Patch一致
+const (
+	DiffReplace = "replace"
+	DiffAdd     = "add"
+	DiffRemove  = "remove"
+)
+
 type DiffItem struct {
-	Column   string
+	Column   string // 字段路径（Go字段名），如：Profile.Name
+	Path     string // 字段路径（json名称），如：profile.name
+	Label    string // 字段名称，取自diff标签或gorm注释，为空时使用Column
+	Op       string // 变更类型：replace|add|remove
 	OldValue any
 	NewValue any
+	OldText  string // 格式化后的原值
+	NewText  string // 格式化后的新值
 	Message  string
 }
 
 func (s *DiffItem) SetMessage() {
-	s.Message = fmt.Sprintf("将%v的值[%v]变更为[%v]；", s.Column, s.OldValue, s.NewValue)
+	var name = s.Label
+	if name == "" {
+		name = s.Column
+	}
+	var oldText, newText = s.OldText, s.NewText
+	if oldText == "" && s.Op != DiffAdd {
+		oldText = fmt.Sprint(s.OldValue)
+	}
+	if newText == "" && s.Op != DiffRemove {
+		newText = fmt.Sprint(s.NewValue)
+	}
+	s.Message = fmt.Sprintf("将%v的值[%v]变更为[%v]；", name, oldText, newText)
+}
+
+// JSON Patch格式的变更项
+type DiffPatch struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"` // 如：/profile/name
+	Label string `json:"label,omitempty"`
+	Old   any    `json:"old,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// 值格式化方法
+type DiffFormatter func(value any) string
+
+var diffFormatters = map[string]DiffFormatter{
+	"money": func(value any) string {
+		return formatDecimal(value, 1)
+	},
+	"cent": func(value any) string {
+		return formatDecimal(value, 100)
+	},
+	"date": func(value any) string {
+		return formatTime(value, DateFormat)
+	},
+	"datetime": func(value any) string {
+		return formatTime(value, DatetimeFormat)
+	},
+}
+
+var diffFormatterLock sync.RWMutex
+
+// 注册值格式化方法，在diff标签中使用：diff:"金额,money"
+func RegisterDiffFormatter(name string, formatter DiffFormatter) {
+	diffFormatterLock.Lock()
+	defer diffFormatterLock.Unlock()
+	diffFormatters[name] = formatter
+}
+
+func getDiffFormatter(name string) DiffFormatter {
+	diffFormatterLock.RLock()
+	defer diffFormatterLock.RUnlock()
+	return diffFormatters[name]
 }
 
+// 比对时忽略的字段
+var diffIgnoreFields = []string{"CreatedAt", "UpdatedAt", "DeletedAt"}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	localTimeType = reflect.TypeOf(LocalTime{})
+	valuerType    = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	diffBoxType   = reflect.TypeOf(DiffBox{})
+)
+
 // 设置新值
 func (s *DiffBox) SetNew(newValue any) {
 	s.New = newValue
@@ -36,56 +134,237 @@ func (s *DiffBox) SetNewAndCompare(newValue any, result func(diff *DiffBox)) {
 	}
 }
 
-// 比较
+// 比较，Old和New可以是结构体或结构体指针，类型不同时无比对结果
 func (s *DiffBox) Compare() {
 	result := map[string]DiffItem{}
+	s.Result = &result
+	s.keys = s.keys[:0]
 	oldVal := reflect.ValueOf(s.Old)
 	newVal := reflect.ValueOf(s.New)
-	if oldVal.Kind() != reflect.Struct || newVal.Kind() != reflect.Struct {
+	if !oldVal.IsValid() || !newVal.IsValid() || oldVal.Type() != newVal.Type() {
 		return
 	}
-	oldType := oldVal.Type()
-	newType := newVal.Type()
-	if oldType != newType {
+	s.compare(diffField{}, oldVal, newVal)
+}
+
+// 比对中的字段信息
+type diffField struct {
+	column string
+	path   string
+	label  string
+	format string
+	whole  bool
+}
+
+// 子项字段，名称为上级名称加子项名称，如：收货地址.城市、标签.0
+func (s diffField) child(column string, path string, label string) diffField {
+	var child = diffField{column: column, path: path, label: label}
+	if s.column != "" {
+		child.column = s.column + "." + column
+		child.path = s.path + "." + path
+	}
+	if s.label != "" {
+		if label == "" {
+			label = column
+		}
+		child.label = s.label + "." + label
+	}
+	return child
+}
+
+func (s *DiffBox) compare(field diffField, oldVal reflect.Value, newVal reflect.Value) {
+	// 指针、接口取实际值，一方为空时整体变更
+	for oldVal.Kind() == reflect.Pointer || oldVal.Kind() == reflect.Interface {
+		if oldVal.IsNil() || newVal.IsNil() {
+			if oldVal.IsNil() != newVal.IsNil() {
+				s.add(field, oldVal, newVal)
+			}
+			return
+		}
+		oldVal, newVal = oldVal.Elem(), newVal.Elem()
+		if oldVal.Type() != newVal.Type() {
+			s.add(field, oldVal, newVal)
+			return
+		}
+	}
+	if field.whole || isDiffLeaf(oldVal.Type()) {
+		if !reflect.DeepEqual(oldVal.Interface(), newVal.Interface()) {
+			s.add(field, oldVal, newVal)
+		}
 		return
 	}
-	for i := 0; i < oldVal.NumField(); i++ {
-		oldField := oldVal.Field(i)
-		newField := newVal.Field(i)
 
-		if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
-			name := oldType.Field(i).Name
-			if IsInSlice(name, []string{"CreatedAt", "UpdatedAt", "DeletedAt"}) {
-				continue
+	switch oldVal.Kind() {
+	case reflect.Struct:
+		s.compareStruct(field, oldVal, newVal)
+	case reflect.Slice, reflect.Array:
+		var size = max(oldVal.Len(), newVal.Len())
+		for i := 0; i < size; i++ {
+			var child = field.child(strconv.Itoa(i), strconv.Itoa(i), "")
+			child.format = field.format
+			switch {
+			case i >= oldVal.Len():
+				s.add(child, reflect.Value{}, newVal.Index(i))
+			case i >= newVal.Len():
+				s.add(child, oldVal.Index(i), reflect.Value{})
+			default:
+				s.compare(child, oldVal.Index(i), newVal.Index(i))
 			}
-			diffItem := DiffItem{
-				Column:   name,
-				OldValue: oldField.Interface(),
-				NewValue: newField.Interface(),
+		}
+	case reflect.Map:
+		var keys = make(map[string]reflect.Value)
+		for _, key := range append(oldVal.MapKeys(), newVal.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		var names = make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var child = field.child(name, name, "")
+			child.format = field.format
+			var oldItem, newItem = oldVal.MapIndex(keys[name]), newVal.MapIndex(keys[name])
+			switch {
+			case !oldItem.IsValid():
+				s.add(child, reflect.Value{}, newItem)
+			case !newItem.IsValid():
+				s.add(child, oldItem, reflect.Value{})
+			default:
+				s.compare(child, oldItem, newItem)
 			}
-			diffItem.SetMessage()
-			result[diffItem.Column] = diffItem
+		}
+	default:
+		if !reflect.DeepEqual(oldVal.Interface(), newVal.Interface()) {
+			s.add(field, oldVal, newVal)
 		}
 	}
-	s.Result = &result
 }
 
-// 判断指定字段是否被修改，被修改返回true
-func (s *DiffBox) IsChange(column string) bool {
+func (s *DiffBox) compareStruct(field diffField, oldVal reflect.Value, newVal reflect.Value) {
+	var typ = oldVal.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		var structField = typ.Field(i)
+		if !structField.IsExported() || IsInSlice(structField.Name, diffIgnoreFields) {
+			continue
+		}
+		// 模型中挂载的DiffBox不参与比对
+		if ft := structField.Type; ft == diffBoxType || ft.Kind() == reflect.Pointer && ft.Elem() == diffBoxType {
+			continue
+		}
+		label, ignore := DiffLabel(structField)
+		if ignore {
+			continue
+		}
+		// 匿名嵌入的结构体字段平铺到当前层级
+		if structField.Anonymous && !isDiffLeaf(structField.Type) {
+			s.compare(field, oldVal.Field(i), newVal.Field(i))
+			continue
+		}
+		var child = field.child(structField.Name, jsonName(structField), label)
+		child.format, child.whole = diffOptions(structField)
+		s.compare(child, oldVal.Field(i), newVal.Field(i))
+	}
+}
+
+// 记录变更项
+func (s *DiffBox) add(field diffField, oldVal reflect.Value, newVal reflect.Value) {
+	var item = DiffItem{
+		Column: field.column,
+		Path:   field.path,
+		Label:  field.label,
+		Op:     DiffReplace,
+	}
+	if isEmptyValue(oldVal) {
+		item.Op = DiffAdd
+	} else if isEmptyValue(newVal) {
+		item.Op = DiffRemove
+	}
+	item.OldValue, item.OldText = diffValue(oldVal, field.format)
+	item.NewValue, item.NewText = diffValue(newVal, field.format)
+	item.SetMessage()
+	if _, ok := (*s.Result)[item.Column]; !ok {
+		s.keys = append(s.keys, item.Column)
+	}
+	(*s.Result)[item.Column] = item
+}
+
+// 按比对顺序返回变更项
+func (s *DiffBox) Items() []DiffItem {
 	if s.Result == nil {
 		s.Compare()
 	}
-	_, ok := (*s.Result)[column]
-	return ok
+	var items = make([]DiffItem, 0, len(*s.Result))
+	for _, key := range s.keys {
+		if item, ok := (*s.Result)[key]; ok {
+			items = append(items, item)
+		}
+	}
+	// 兼容直接设置Result的情况
+	if len(items) < len(*s.Result) {
+		var keys = make([]string, 0, len(*s.Result))
+		for key := range *s.Result {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items = items[:0]
+		for _, key := range keys {
+			items = append(items, (*s.Result)[key])
+		}
+	}
+	return items
 }
 
-// 判断指定字段是否被修改，被修改则进行相应处理
-func (s *DiffBox) IsModify(column string, callback func()) {
+// 返回JSON Patch格式的变更项
+func (s *DiffBox) Patch() []DiffPatch {
+	var items = s.Items()
+	var patches = make([]DiffPatch, 0, len(items))
+	for _, item := range items {
+		var patch = DiffPatch{
+			Op:    item.Op,
+			Path:  "/" + strings.ReplaceAll(item.Path, ".", "/"),
+			Label: item.Label,
+		}
+		if item.Op != DiffAdd {
+			patch.Old = item.OldValue
+		}
+		if item.Op != DiffRemove {
+			patch.Value = item.NewValue
+		}
+		patches = append(patches, patch)
+	}
+	return patches
+}
+
+// 返回JSON Patch格式的变更项json字符串
+func (s *DiffBox) PatchJson() string {
+	b, err := json.Marshal(s.Patch())
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// 判断指定字段是否被修改，被修改返回true，column可以是字段路径（Profile.Name）或json路径（profile.name）
+// 指定上级字段时，任一子项被修改即返回true
+func (s *DiffBox) IsChange(column string) bool {
 	if s.Result == nil {
 		s.Compare()
 	}
-	_, ok := (*s.Result)[column]
-	if ok && callback != nil {
+	if _, ok := (*s.Result)[column]; ok {
+		return true
+	}
+	for key, item := range *s.Result {
+		if item.Path == column || strings.HasPrefix(key, column+".") || strings.HasPrefix(item.Path, column+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断指定字段是否被修改，被修改则进行相应处理
+func (s *DiffBox) IsModify(column string, callback func()) {
+	if s.IsChange(column) && callback != nil {
 		callback()
 	}
 }
@@ -100,20 +379,21 @@ func (s *DiffBox) IsChangeOr(column ...string) bool {
 	return false
 }
 
-// 批量且判断（只要有一个未修改则返回假）
+// 批量且判断（只要有一个未修改则返回假，全部被修改返回真，未传字段返回假）
+// 注意：旧版本始终返回false，依赖该行为的调用需调整
 func (s *DiffBox) IsChangeAnd(column ...string) bool {
 	for _, item := range column {
 		if !s.IsChange(item) {
 			return false
 		}
 	}
-	return false
+	return len(column) > 0
 }
 
 // 返回字符串结果
 func (s *DiffBox) ResultContent() string {
 	var text string
-	for _, item := range *s.Result {
+	for _, item := range s.Items() {
 		text += item.Message
 	}
 	if text == "" {
@@ -121,3 +401,135 @@ func (s *DiffBox) ResultContent() string {
 	}
 	return text
 }
+
+// 获取字段的显示名称，优先使用diff:"名称"标签，其次使用gorm标签中的comment，diff:"-"表示不参与比对
+func DiffLabel(field reflect.StructField) (label string, ignore bool) {
+	if tag, ok := field.Tag.Lookup("diff"); ok {
+		label, _, _ = strings.Cut(tag, ",")
+		if label == "-" {
+			return "", true
+		}
+		if label != "" {
+			return label, false
+		}
+	}
+	for _, item := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if key, value, ok := strings.Cut(item, ":"); ok && strings.EqualFold(strings.TrimSpace(key), "comment") {
+			return strings.Trim(value, "'"), false
+		}
+	}
+	return "", false
+}
+
+// diff标签中的格式化方法和整体比对选项
+func diffOptions(field reflect.StructField) (format string, whole bool) {
+	var tag = field.Tag.Get("diff")
+	_, options, _ := strings.Cut(tag, ",")
+	for _, option := range strings.Split(options, ",") {
+		switch option = strings.TrimSpace(option); option {
+		case "":
+		case "whole":
+			whole = true
+		default:
+			format = option
+		}
+	}
+	return
+}
+
+// 不展开比对的类型：基础类型、时间、实现了driver.Valuer的类型（如gorm.DeletedAt、JsonObject）
+func isDiffLeaf(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == timeType || typ == localTimeType || typ.Implements(valuerType) || reflect.PointerTo(typ).Implements(valuerType) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return false
+	case reflect.Slice, reflect.Array:
+		return typ.Elem().Kind() == reflect.Uint8
+	}
+	return true
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	return !value.IsValid() || (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface || value.Kind() == reflect.Map || value.Kind() == reflect.Slice) && value.IsNil()
+}
+
+// 返回原始值和格式化后的文本
+func diffValue(value reflect.Value, format string) (any, string) {
+	if isEmptyValue(value) {
+		return nil, ""
+	}
+	var raw = value.Interface()
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	var data = value.Interface()
+	if format != "" {
+		if formatter := getDiffFormatter(format); formatter != nil {
+			return raw, formatter(data)
+		}
+	}
+	switch v := data.(type) {
+	case LocalTime:
+		return raw, formatTime(v, DatetimeFormat)
+	case time.Time:
+		return raw, formatTime(v, DatetimeFormat)
+	}
+	return raw, fmt.Sprint(data)
+}
+
+func formatTime(value any, layout string) string {
+	var t time.Time
+	switch v := value.(type) {
+	case LocalTime:
+		t = time.Time(v)
+	case *LocalTime:
+		if v == nil {
+			return ""
+		}
+		t = time.Time(*v)
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		t = *v
+	default:
+		return fmt.Sprint(value)
+	}
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(layout)
+}
+
+// 数值除以unit后保留两位小数
+func formatDecimal(value any, unit float64) string {
+	var v = reflect.Indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatFloat(float64(v.Int())/unit, 'f', 2, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatFloat(float64(v.Uint())/unit, 'f', 2, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float()/unit, 'f', 2, 64)
+	case reflect.String:
+		if f, err := strconv.ParseFloat(v.String(), 64); err == nil {
+			return strconv.FormatFloat(f/unit, 'f', 2, 64)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// 字段的json名称，未设置json标签时使用字段名
+func jsonName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
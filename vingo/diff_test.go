@@ -0,0 +1,72 @@
+package vingo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type diffProfile struct {
+	Name string
+	City string
+}
+
+type diffUser struct {
+	Id        uint
+	Name      string `gorm:"comment:姓名"`
+	Age       int    `diff:"年龄"`
+	Password  string `diff:"-"`
+	Profile   diffProfile
+	Address   diffProfile `diff:",whole"`
+	UpdatedAt time.Time
+}
+
+// 基础类型字段的键与旧版本一致，为字段名
+func TestDiffFlatKeys(t *testing.T) {
+	var box = DiffBox{
+		Old: diffUser{Id: 1, Name: "张三", Age: 18, Password: "a", UpdatedAt: time.Now()},
+		New: diffUser{Id: 1, Name: "李四", Age: 20, Password: "b", UpdatedAt: time.Now().Add(time.Hour)},
+	}
+	box.Compare()
+	var keys = make([]string, 0)
+	for _, item := range box.Items() {
+		keys = append(keys, item.Column)
+	}
+	if !reflect.DeepEqual(keys, []string{"Name", "Age"}) {
+		t.Fatalf("比对结果的键错误：%v", keys)
+	}
+	var name = (*box.Result)["Name"]
+	if name.Label != "姓名" || name.OldValue != "张三" || name.NewValue != "李四" || name.Message != "将姓名的值[张三]变更为[李四]；" {
+		t.Fatalf("比对项错误：%+v", name)
+	}
+	if (*box.Result)["Age"].Message != "将年龄的值[18]变更为[20]；" {
+		t.Fatalf("比对项错误：%+v", (*box.Result)["Age"])
+	}
+	if !box.IsChange("Name") || box.IsChange("Password") || box.IsChange("UpdatedAt") || box.IsChange("Id") {
+		t.Fatal("IsChange判断错误")
+	}
+	if !box.IsChangeAnd("Name", "Age") || box.IsChangeAnd("Name", "Id") || box.IsChangeAnd() {
+		t.Fatal("IsChangeAnd判断错误")
+	}
+	if !box.IsChangeOr("Id", "Age") || box.IsChangeOr("Id") {
+		t.Fatal("IsChangeOr判断错误")
+	}
+	if box.ResultContent() != "将姓名的值[张三]变更为[李四]；将年龄的值[18]变更为[20]；" {
+		t.Fatalf("比对内容错误：%v", box.ResultContent())
+	}
+}
+
+// 嵌套结构体展开为字段路径，whole标签整体比对
+func TestDiffNested(t *testing.T) {
+	var box = DiffBox{
+		Old: &diffUser{Profile: diffProfile{Name: "a"}, Address: diffProfile{City: "x"}},
+		New: &diffUser{Profile: diffProfile{Name: "b"}, Address: diffProfile{City: "y"}},
+	}
+	box.Compare()
+	if !box.IsChange("Profile.Name") || !box.IsChange("Profile") || box.IsChange("Profile.City") {
+		t.Fatalf("嵌套字段判断错误：%v", *box.Result)
+	}
+	if _, ok := (*box.Result)["Address"]; !ok || box.IsChange("Address.City") {
+		t.Fatalf("整体比对错误：%v", *box.Result)
+	}
+}
@@ -25,6 +25,10 @@ func ExceptionHandler(c *gin.Context) {
 				context.Response(&ResponseData{Message: t.Message, Status: 200, Error: 2, ErrorType: "业务错误"})
 			case *exception.BackException:
 				context.Response(&ResponseData{Message: t.Message, Status: 200, Error: 3, ErrorType: "业务错误"})
+			case *ParamException:
+				context.Response(&ResponseData{Message: t.Message, Status: 200, Error: 1, ErrorType: "参数错误", Data: map[string]any{"field": t.Field}})
+			case *ConflictException:
+				context.Response(&ResponseData{Message: t.Message, Status: 200, Error: 4, ErrorType: "数据冲突"})
 			case *exception.AuthException:
 				context.Response(&ResponseData{Message: t.Message, Status: 401, Error: 1})
 			default:
@@ -36,6 +40,25 @@ func ExceptionHandler(c *gin.Context) {
 	c.Next()
 }
 
+// 请求参数错误
+type ParamException struct {
+	Field   string // 错误的参数名
+	Message string
+}
+
+func (s *ParamException) Error() string {
+	return s.Message
+}
+
+// 数据冲突错误，如乐观锁版本号校验失败，前端应刷新数据后重试
+type ConflictException struct {
+	Message string
+}
+
+func (s *ConflictException) Error() string {
+	return s.Message
+}
+
 func ExceptionCatch(s string, emit bool) {
 	if err := recover(); err != nil {
 		LogError(fmt.Sprintf("%v：%v", s, err))